@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,34 +13,149 @@ import (
 
 	"gpu-reclaimer-agent/internal/agent"
 	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/kube"
 	"gpu-reclaimer-agent/internal/logging"
+	"gpu-reclaimer-agent/internal/metrics"
 )
 
 func main() {
-	cfg := config.FromEnvAndFlags(os.Args[1:])
 	logger := logging.NewJSONLogger(os.Stdout)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		exitConfigError(logger, err)
+	}
+	warnFileOverrides(logger, cfg)
+
+	m := metrics.New()
+
+	hostname := cfg.NodeName
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 
-	hostname, _ := os.Hostname()
-	ag := agent.New(agent.Options{
+	opts := agent.Options{
 		Config:   cfg,
 		NodeName: hostname,
 		Logger:   logger,
-	})
+		Metrics:  m,
+	}
+	kc, err := kube.InClusterClient()
+	if err != nil {
+		logger.Warn(map[string]any{"msg": "kubernetes api unavailable: pod annotations cannot be read, no pod will be reclaimed", "error": err.Error()})
+	} else {
+		pods := kube.NewPodCache(kc, hostname)
+		go pods.Run(ctx)
+		opts.Pods = pods
+		nss := kube.NewNamespaceCache(kc)
+		go nss.Run(ctx)
+		opts.Namespaces = nss
+		if cfg.EventsEnabled {
+			opts.Events = kube.NewEventRecorder(kc, "gpu-reclaimer-agent", hostname)
+		}
+	}
+	ag, err := agent.New(opts)
+	if err != nil {
+		exitConfigError(logger, err)
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
+	if cfg.ConfigFile != "" {
+		go config.WatchFile(ctx, cfg.ConfigFile, 10*time.Second, func() {
+			// Env and flags still apply on top of the re-read file.
+			next, err := config.Load(os.Args[1:])
+			if err == nil {
+				warnFileOverrides(logger, next)
+				err = ag.UpdateConfig(next)
+			}
+			if err != nil {
+				logger.Error(map[string]any{"msg": "config reload rejected; keeping previous config", "file": cfg.ConfigFile, "error": err.Error()})
+			}
+		})
+	}
+
+	// Node canary gate: follow our own Node's labels. The watch runs even
+	// without a selector so one added by a config reload takes effect.
+	// Without API access the agent stays observe-only when a selector is
+	// configured.
+	if kc != nil {
+		nodes := kube.NewInformer(kc, "/api/v1/nodes", "metadata.name="+hostname)
+		nodes.OnChange(func(o kube.Object, deleted bool) {
+			if o.Metadata.Name == hostname {
+				ag.ObserveNode(o.Metadata.Labels, !deleted)
+			}
+		})
+		go nodes.Run(ctx)
+	}
+
+	if cfg.MetricsAddr != "" {
+		go serveMetrics(ctx, cfg.MetricsAddr, m, logger)
+	}
 
 	logger.Info(map[string]any{
-		"msg":       "gpu-reclaimer-agent starting",
-		"node":      hostname,
-		"dry_run":   cfg.DryRun,
+		"msg":        "gpu-reclaimer-agent starting",
+		"node":       hostname,
+		"dry_run":    cfg.DryRun,
+		"node_gate":  cfg.NodeSelectorLabel,
 		"interval_s": int(cfg.SampleInterval.Seconds()),
 	})
 
-	if err := ag.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+	err = ag.Run(ctx)
+	if cErr := ag.Close(); cErr != nil {
+		logger.Warn(map[string]any{"msg": "agent shutdown incomplete", "error": cErr.Error()})
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
 		logger.Error(map[string]any{"msg": "gpu-reclaimer-agent exited with error", "error": err.Error()})
 		// give log collector a chance
 		time.Sleep(250 * time.Millisecond)
 		os.Exit(1)
 	}
 }
+
+func serveMetrics(ctx context.Context, addr string, m *metrics.Metrics, logger *logging.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) })
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info(map[string]any{"msg": "metrics endpoint listening", "addr": addr})
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(map[string]any{"msg": "metrics endpoint failed", "addr": addr, "error": err.Error()})
+	}
+}
+
+// warnFileOverrides reports config file keys that env or flags override, so
+// an edit to them in the file is not silently ignored.
+func warnFileOverrides(logger *logging.Logger, cfg config.Config) {
+	if len(cfg.FileOverrides) == 0 {
+		return
+	}
+	logger.Warn(map[string]any{"msg": "config file keys overridden by env or flags", "file": cfg.ConfigFile, "keys": cfg.FileOverrides})
+}
+
+// exitConfigError refuses to start, listing every config problem both as a
+// structured log line and in plain text on stderr for `kubectl logs`.
+func exitConfigError(logger *logging.Logger, err error) {
+	fields := map[string]any{"msg": "refusing to start: invalid configuration", "error": err.Error()}
+	var ve *config.ValidationError
+	if errors.As(err, &ve) {
+		fields["problems"] = ve.Problems
+		fmt.Fprintln(os.Stderr, "gpu-reclaimer-agent: invalid configuration:")
+		for _, p := range ve.Problems {
+			fmt.Fprintln(os.Stderr, "  - "+p)
+		}
+	}
+	logger.Error(fields)
+	os.Exit(2)
+}
@@ -1,5 +1,22 @@
 module gpu-reclaimer-agent
 
-go 1.18
+go 1.21
 
-require github.com/NVIDIA/go-nvml v0.13.0-1
+require (
+	github.com/NVIDIA/go-nvml v0.13.0-1
+	google.golang.org/grpc v1.58.3
+	gopkg.in/yaml.v3 v3.0.1
+	k8s.io/cri-api v0.29.3
+	k8s.io/kubelet v0.29.3
+)
+
+require (
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230822172742-b8732ec3820d // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
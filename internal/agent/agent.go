@@ -2,67 +2,385 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/clock"
 	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/dcgm"
 	"gpu-reclaimer-agent/internal/idle"
+	"gpu-reclaimer-agent/internal/kube"
 	"gpu-reclaimer-agent/internal/logging"
+	"gpu-reclaimer-agent/internal/metrics"
+	nvmlwrap "gpu-reclaimer-agent/internal/nvml"
+	"gpu-reclaimer-agent/internal/podresources"
+	"gpu-reclaimer-agent/internal/policy"
+	"gpu-reclaimer-agent/internal/reclaim"
+	"gpu-reclaimer-agent/internal/recording"
 	"gpu-reclaimer-agent/internal/sampling"
 	"gpu-reclaimer-agent/internal/smi"
-	nvmlwrap "gpu-reclaimer-agent/internal/nvml"
 )
 
 type Options struct {
 	Config   config.Config
 	NodeName string
 	Logger   *logging.Logger
+	// Metrics is optional; a private registry is used when nil.
+	Metrics *metrics.Metrics
+	// Events is optional; nil disables Kubernetes Events.
+	Events EventRecorder
+	// Pods is optional; without it the pod-level annotation cannot be read
+	// and every candidate is skipped.
+	Pods PodSource
+	// Namespaces is optional; without it (or before it syncs) candidates a
+	// tier with a namespace label selector could match are skipped.
+	Namespaces NamespaceSource
+	// Attributor is optional; defaults to the /proc + CRI resolver.
+	Attributor Attributor
+	// Clock is optional; defaults to wall time, or to the recording's time
+	// when replaying with a virtual clock.
+	Clock clock.Clock
+	// Processes is optional; defaults to /proc when Attributor is not set.
+	// Without it reclaim only signals the GPU PIDs whatever the target.
+	Processes ProcessTreeSource
+	// PodResources is optional; defaults to the kubelet socket in
+	// Config.PodResourcesSocket, and nil disables the allocation cross-check.
+	PodResources PodResourcesLister
+	// Sampler is optional and replaces the one Config.Sampler selects.
+	Sampler sampling.Sampler
+	// ProcRoot is optional: a procfs the default resolver and the reclaim
+	// executor both read instead of Config.ProcRoot and "/proc", e.g. a
+	// synthetic tree.
+	ProcRoot string
+	// Signal is optional (default syscall.Kill); reclaim uses it to signal
+	// and probe PIDs.
+	Signal func(pid int, sig syscall.Signal) error
+}
+
+// ProcessTreeSource snapshots the host process tree.
+type ProcessTreeSource interface {
+	ProcessTree() (*attribution.ProcessTree, error)
+}
+
+// PodResourcesLister lists the node's device allocations.
+type PodResourcesLister interface {
+	List(ctx context.Context) ([]podresources.Allocation, error)
+}
+
+// Attributor maps a host PID to the pod/container owning it.
+type Attributor interface {
+	ResolvePID(ctx context.Context, pid int) (attribution.Attribution, error)
+}
+
+// NamespaceSource looks up Namespace labels for policy tier selection.
+type NamespaceSource interface {
+	HasSynced() bool
+	NamespaceLabels(name string) (map[string]string, bool)
+}
+
+// PodSource looks up pod metadata (labels/annotations) from the API server.
+type PodSource interface {
+	HasSynced() bool
+	PodByUID(uid string) (kube.ObjectMeta, bool)
+	Lookup(ctx context.Context, uid, namespace, name string) (kube.ObjectMeta, bool, error)
+}
+
+// EventRecorder posts Events on the pod owning a candidate.
+type EventRecorder interface {
+	PodWarning(ctx context.Context, namespace, name, uid, reason, message string) error
 }
 
 type Agent struct {
 	cfg     config.Config
 	node    string
 	log     *logging.Logger
-	sampler sampling.Sampler
-	attrib  *attribution.Resolver
-	tracker *idle.Tracker
+	metrics *metrics.Metrics
+	events  EventRecorder
+	pods    PodSource
+	nsSrc   NamespaceSource
+	policy  *policy.Resolver
+	protect *policy.Protection
+	sampler *serialSampler
+	attrib  Attributor
+	// The resolver New built, closed by Close; nil when the caller passed
+	// an Attributor.
+	resolver *attribution.Resolver
+	procs    ProcessTreeSource
+	clock    clock.Clock
+	tracker  *idle.Tracker
+	// Time of the last tick's snapshot; the clock may have moved on since,
+	// e.g. to a validation re-sample during replay.
+	lastTick time.Time
+	reclaim  *reclaim.Executor
+	verify   *reclaim.Verifier
+
+	// Reclaims run off the tick loop (see startReclaim); inflight holds the
+	// pods being reclaimed so a pod is never reclaimed twice at once.
+	reclaims   sync.WaitGroup
+	inflightMu sync.Mutex
+	inflight   map[string]bool
+
+	// Devices reported unhealthy in the previous tick, by ID, so the log
+	// records transitions rather than every tick.
+	unhealthy map[string]string
+	// MIG slices in use whose utilization the sampler cannot read, by ID,
+	// reported on the same terms.
+	migUnknown map[string]bool
+	// Whether GPU PIDs were unreachable across PID namespaces last tick.
+	pidnsFailing bool
+
+	// Allocation cross-check (see podresources.go); the maps hold what was
+	// reported in the previous tick.
+	podRes        PodResourcesLister
+	podResFailing bool
+	mismatched    map[string]string
+	unusedAllocs  map[string]string
 
 	allowlist *regexp.Regexp
+
+	// Handed to every reclaim.Executor built from the config.
+	procRoot string
+	signal   func(pid int, sig syscall.Signal) error
+
+	// Hot-reloaded settings are handed to the Run goroutine and applied
+	// between ticks, so tick never sees a half-updated config.
+	reloadCh chan *settings
+
+	// Node canary gate (PRD FR-13): when Config.NodeSelectorLabel is set the
+	// agent only enforces while its Node carries that label. Guarded by
+	// modeMu since the Node watch runs on its own goroutine.
+	modeMu      sync.Mutex
+	nodeGate    bool
+	nodeSel     kube.Selector
+	nodeSelErr  error
+	nodeLabels  map[string]string
+	nodePresent bool
+	enforcing   bool
 }
 
-func New(opts Options) *Agent {
-	allow := regexp.MustCompile(opts.Config.ProcessAllowlistRegex)
+// New builds an agent; it fails if opts.Config does not validate.
+func New(opts Options) (*Agent, error) {
+	st, err := compileSettings(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 	var sampler sampling.Sampler
-	switch strings.ToLower(strings.TrimSpace(opts.Config.Sampler)) {
-	case "smi", "nvidia-smi", "nvidiasmi":
+	m := opts.Metrics
+	if m == nil {
+		m = metrics.New()
+	}
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	switch kind := strings.ToLower(strings.TrimSpace(opts.Config.Sampler)); {
+	case opts.Sampler != nil:
+		sampler = opts.Sampler
+	case kind == "smi" || kind == "nvidia-smi" || kind == "nvidiasmi":
 		sampler = smi.New("nvidia-smi")
+	case kind == "dcgm":
+		sampler = dcgm.New(opts.Config.DCGMEndpoint)
+	case kind == "auto":
+		fb := sampling.NewFallback(newNVML(opts.Config), smi.New("nvidia-smi"))
+		fb.Clock = clk
+		fb.OnSwitch = samplerSwitchLogger(opts.Logger, m, opts.NodeName)
+		sampler = fb
+	case kind == "replay":
+		r, err := recording.OpenReplay(opts.Config.ReplayFile, opts.Config.ReplayVirtualClock)
+		if err != nil {
+			return nil, err
+		}
+		sampler = r
+		if opts.Clock == nil {
+			clk = r
+		}
+		if opts.Attributor == nil {
+			opts.Attributor = r
+		}
+		// This host's allocations say nothing about the recorded PIDs.
+		opts.Config.PodResourcesSocket = ""
 	default:
-		sampler = nvmlwrap.New()
+		sampler = newNVML(opts.Config)
+	}
+	attrib := opts.Attributor
+	procs := opts.Processes
+	var resolver *attribution.Resolver
+	if attrib == nil {
+		res := attribution.NewResolver(opts.Config.CRIEndpoint)
+		resolver = res
+		res.Clock = clk
+		if opts.Config.ProcRoot != "" {
+			res.ProcRoot = opts.Config.ProcRoot
+		}
+		if opts.ProcRoot != "" {
+			res.ProcRoot = opts.ProcRoot
+		}
+		attrib = res
+		if procs == nil {
+			procs = res
+		}
+	}
+	if path := opts.Config.Record; path != "" {
+		w, err := recording.NewRotatingWriter(path, int64(opts.Config.RecordMaxMB)<<20, opts.Config.RecordMaxFiles)
+		if err != nil {
+			_ = sampler.Close()
+			return nil, fmt.Errorf("open record file: %w", err)
+		}
+		rec := recording.NewRecorder(sampler, w)
+		rec.Clock = clk
+		rec.OnError = recordErrorLogger(opts.Logger, clk, opts.NodeName, path)
+		w.OnRotateError = rec.OnError
+		sampler, attrib = rec, rec.Attributor(attrib)
 	}
-	return &Agent{
-		cfg:       opts.Config,
-		node:      opts.NodeName,
-		log:       opts.Logger,
-		sampler:   sampler,
-		attrib:    attribution.NewResolver(opts.Config.CRIEndpoint),
-		tracker:   idle.NewTracker(opts.Config.IdleMinutes, opts.Config.ConsecutiveIdleSamples, opts.Config.SampleInterval),
-		allowlist: allow,
+	ag := &Agent{
+		node:     opts.NodeName,
+		log:      opts.Logger,
+		metrics:  m,
+		events:   opts.Events,
+		pods:     opts.Pods,
+		nsSrc:    opts.Namespaces,
+		sampler:  &serialSampler{Sampler: sampler},
+		attrib:   attrib,
+		resolver: resolver,
+		clock:    clk,
+		podRes:   opts.PodResources,
+		procs:    procs,
+		procRoot: opts.ProcRoot,
+		signal:   opts.Signal,
+		tracker:  idle.NewTracker(opts.Config.IdleMinutes, opts.Config.ConsecutiveIdleSamples, opts.Config.SampleInterval),
+		reloadCh: make(chan *settings, 1),
+		inflight: map[string]bool{},
+	}
+	if ag.podRes == nil && opts.Config.PodResourcesSocket != "" {
+		ag.podRes = podresources.New(opts.Config.PodResourcesSocket)
+	}
+	ag.applySettings(st)
+	return ag, nil
+}
+
+// Close waits for in-flight reclaims, then releases the sampler, the CRI
+// connection and the kubelet PodResources connection. Call it once Run has
+// returned.
+func (a *Agent) Close() error {
+	a.reclaims.Wait()
+	err := a.sampler.Close()
+	if a.resolver != nil {
+		if rErr := a.resolver.Close(); err == nil {
+			err = rErr
+		}
+	}
+	if c, ok := a.podRes.(io.Closer); ok {
+		if pErr := c.Close(); err == nil {
+			err = pErr
+		}
+	}
+	return err
+}
+
+// newNVML reads per-process utilization over at least one sample interval,
+// so candidate validation right after a tick still sees the last interval.
+func newNVML(cfg config.Config) *nvmlwrap.Client {
+	c := nvmlwrap.New()
+	c.ProcUtilWindow = cfg.SampleInterval
+	return c
+}
+
+// samplerSwitchLogger reports the auto sampler moving between NVML and
+// nvidia-smi.
+func samplerSwitchLogger(log *logging.Logger, m *metrics.Metrics, node string) func(string, error) {
+	return func(active string, err error) {
+		m.SetSamplerFallback(err != nil)
+		if err != nil {
+			log.Warn(map[string]any{"msg": "gpu sampler failed, falling back", "node": node, "sampler": active, "error": err.Error()})
+			return
+		}
+		log.Info(map[string]any{"msg": "gpu sampler recovered", "node": node, "sampler": active})
+	}
+}
+
+// recordErrorLogger warns about a failing record file at most once a minute,
+// so a full disk does not flood the log every tick.
+func recordErrorLogger(log *logging.Logger, clk clock.Clock, node, path string) func(error) {
+	var last time.Time
+	return func(err error) {
+		now := clk.Now()
+		if now.Sub(last) < time.Minute {
+			return
+		}
+		last = now
+		log.Warn(map[string]any{"msg": "record write failed", "node": node, "file": path, "error": err.Error()})
+	}
+}
+
+// ObserveNode re-evaluates the node gate from the agent's own Node object.
+// present=false (node deleted/unknown) switches to observe-only.
+func (a *Agent) ObserveNode(labels map[string]string, present bool) {
+	a.modeMu.Lock()
+	a.nodeLabels, a.nodePresent = labels, present
+	a.modeMu.Unlock()
+	a.evaluateNodeGate()
+}
+
+func (a *Agent) evaluateNodeGate() {
+	a.modeMu.Lock()
+	enforce := true
+	if a.nodeGate {
+		// An unparseable gate keeps the agent observe-only rather than enforcing everywhere.
+		enforce = a.nodePresent && a.nodeSelErr == nil && a.nodeSel.Matches(a.nodeLabels)
+	}
+	changed := enforce != a.enforcing
+	a.enforcing = enforce
+	sel := a.nodeSel.String()
+	a.modeMu.Unlock()
+
+	a.metrics.SetEnforcing(enforce)
+	if changed {
+		a.log.Info(map[string]any{"msg": "agent mode changed", "node": a.node, "mode": modeName(enforce), "node_selector": sel})
 	}
 }
 
+func (a *Agent) isEnforcing() bool {
+	a.modeMu.Lock()
+	defer a.modeMu.Unlock()
+	return a.enforcing
+}
+
+func modeName(enforce bool) string {
+	if enforce {
+		return "enforce"
+	}
+	return "observe"
+}
+
 func (a *Agent) Run(ctx context.Context) error {
 	ticker := time.NewTicker(a.cfg.SampleInterval)
 	defer ticker.Stop()
-	defer func() { _ = a.sampler.Close() }()
+	// Let in-flight reclaims see ctx is done and finish before Run returns
+	// and the caller closes the sampler they verify with.
+	defer a.reclaims.Wait()
 
 	a.log.Info(map[string]any{"msg": "gpu sampler selected", "node": a.node, "sampler": a.sampler.Name()})
 
+	a.restoreState(ctx)
+
+	if r, ok := a.sampler.Sampler.(*recording.Replay); ok && r.VirtualClock() {
+		return a.replay(ctx)
+	}
+
 	// First sample immediately.
 	if err := a.tick(ctx); err != nil {
+		if errors.Is(err, io.EOF) {
+			return a.replayDone()
+		}
 		a.log.Warn(map[string]any{"msg": "initial tick failed", "error": err.Error()})
 	}
 
@@ -70,21 +388,60 @@ func (a *Agent) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case st := <-a.reloadCh:
+			prev := a.cfg.SampleInterval
+			a.applySettings(st)
+			if a.cfg.SampleInterval != prev {
+				ticker.Reset(a.cfg.SampleInterval)
+			}
+			a.log.Info(map[string]any{"msg": "config reloaded", "node": a.node, "dry_run": a.cfg.DryRun, "interval_s": int(a.cfg.SampleInterval.Seconds()), "policies": len(a.cfg.Policies)})
 		case <-ticker.C:
 			if err := a.tick(ctx); err != nil {
+				if errors.Is(err, io.EOF) {
+					return a.replayDone()
+				}
 				a.log.Warn(map[string]any{"msg": "tick failed", "error": err.Error()})
 			}
 		}
 	}
 }
 
+// replay drives ticks back-to-back while the recording lasts; time comes
+// from the recorded timestamps, so no wall-clock pacing is needed.
+func (a *Agent) replay(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case st := <-a.reloadCh:
+			a.applySettings(st)
+		default:
+		}
+		if err := a.tick(ctx); err != nil {
+			if errors.Is(err, io.EOF) {
+				return a.replayDone()
+			}
+			a.log.Warn(map[string]any{"msg": "tick failed", "error": err.Error()})
+		}
+	}
+}
+
+func (a *Agent) replayDone() error {
+	a.log.Info(map[string]any{"msg": "replay finished", "node": a.node, "idle_candidates": a.tracker.ReportedCount(a.lastTick)})
+	return nil
+}
+
 type podAgg struct {
 	key      idle.PodKey
 	gpusSet  map[int]struct{}
 	pidsSet  map[int]struct{}
 	cmdlines []string
 
-	gpuIdle map[int]bool
+	// What the pod does on each GPU it touches; the tracker judges it
+	// against the pod's own policy threshold.
+	usage map[string]*idle.GPUUsage
+	// UUIDs of the devices it touches, for the allocation cross-check.
+	uuids map[string]struct{}
 }
 
 func (a *Agent) tick(ctx context.Context) error {
@@ -92,19 +449,29 @@ func (a *Agent) tick(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	a.metrics.ObserveSnapshot(snap)
+	a.logHealth(snap)
+	a.logMIGUtil(snap)
 
-	now := time.Now()
+	now := a.clock.Now()
+	a.lastTick = now
 	pods := map[string]*podAgg{}
-	attribFail := 0
+	attribFail, resolved := 0, 0
+	var nsErr error
 
 	for _, g := range snap.GPUs {
-		gpuIsIdle := int(g.UtilGPU) < a.cfg.GPUUtilThresholdPct
 		for _, p := range g.ComputeProcs {
 			// Best-effort attribution; if we can't attribute, we won't act.
 			pid := p.PID
 			attrCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 			attr, err := a.attrib.ResolvePID(attrCtx, pid)
 			cancel()
+			if errors.Is(err, attribution.ErrPIDNamespace) {
+				// A deployment problem, not a per-PID one; reported once below.
+				attribFail++
+				nsErr = err
+				continue
+			}
 			if err != nil {
 				attribFail++
 				a.log.Warn(map[string]any{
@@ -117,66 +484,140 @@ func (a *Agent) tick(ctx context.Context) error {
 				continue
 			}
 
+			resolved++
 			if attr.Cmdline != "" && a.allowlist.MatchString(attr.Cmdline) {
 				// Never consider system allowlisted processes.
 				continue
 			}
 
 			k := idle.PodKey{UID: attr.PodUID, Namespace: attr.PodNamespace, Name: attr.PodName, ContainerID: attr.ContainerID}
+			if a.pods != nil && k.UID != "" && (k.Namespace == "" || k.Name == "") {
+				if meta, ok := a.pods.PodByUID(k.UID); ok {
+					k.Namespace, k.Name = meta.Namespace, meta.Name
+				}
+			}
 			ks := podKeyString(k)
 			agg := pods[ks]
 			if agg == nil {
 				agg = &podAgg{
-					key:     k,
+					key:      k,
 					gpusSet:  map[int]struct{}{},
 					pidsSet:  map[int]struct{}{},
-					gpuIdle:  map[int]bool{},
+					usage:    map[string]*idle.GPUUsage{},
+					uuids:    map[string]struct{}{},
 					cmdlines: nil,
 				}
 				pods[ks] = agg
 			}
 			agg.gpusSet[g.Index] = struct{}{}
 			agg.pidsSet[pid] = struct{}{}
+			if g.UUID != "" {
+				agg.uuids[g.UUID] = struct{}{}
+			}
 			if attr.Cmdline != "" {
 				agg.cmdlines = append(agg.cmdlines, attr.Cmdline)
 			}
-			// If the pod touches this GPU, its idleness depends on this GPU.
-			agg.gpuIdle[g.Index] = gpuIsIdle
+			// If the pod touches this GPU (or MIG slice), its idleness
+			// depends on it.
+			u := agg.usage[g.ID()]
+			if u == nil {
+				u = newUsage(g)
+				agg.usage[g.ID()] = u
+			}
+			addProcUsage(u, p)
 		}
 	}
 
 	if attribFail > 0 {
+		a.metrics.AddAttributionFail(attribFail)
 		a.log.Info(map[string]any{"msg": "pid attribution failures in tick", "node": a.node, "count": attribFail})
 	}
+	a.reportPIDNamespace(nsErr, resolved)
+
+	allocs := a.listAllocations(ctx)
+	mismatched := map[string]string{}
 
 	for _, agg := range pods {
+		mismatch := allocationMismatch(agg.key, agg.uuids, allocs)
+		if mismatch != "" {
+			mismatched[podKeyString(agg.key)] = mismatch
+		}
 		pids := setToSortedInts(agg.pidsSet)
 		gpus := setToSortedInts(agg.gpusSet)
-		idleNow := true
-		for _, gi := range gpus {
-			if ok, exists := agg.gpuIdle[gi]; !exists || !ok {
-				idleNow = false
-				break
-			}
+		pol, polKnown := a.policyFor(agg.key)
+		devices := make([]string, 0, len(agg.usage))
+		for id := range agg.usage {
+			devices = append(devices, id)
+		}
+		sort.Strings(devices)
+		usage := make([]idle.GPUUsage, 0, len(devices))
+		for _, id := range devices {
+			usage = append(usage, *agg.usage[id])
 		}
 
 		cand := a.tracker.Observe(idle.Observation{
 			Key:      agg.key,
 			SeenAt:   now,
-			Idle:     idleNow,
+			Usage:    usage,
 			GPUs:     gpus,
+			Devices:  devices,
 			PIDs:     pids,
 			Cmdlines: limitStrings(agg.cmdlines, 5),
+			Policy:   pol,
 		})
 
 		if cand == nil {
 			continue
 		}
 
+		reason, detail := a.skipReason(ctx, *cand)
+		if reason == "" && !polKnown {
+			// Judged against the default policy; the pod's own tier may
+			// allow more idle time.
+			reason = "namespace_labels_unknown"
+			if cand.Key.Namespace == "" {
+				reason = "pod_namespace_unknown"
+			}
+		}
+		if reason == "" && mismatch != "" {
+			// Either attribution or the device plugin's view is wrong;
+			// don't kill on a guess.
+			reason, detail = "podresources_mismatch", mismatch
+		}
+		if reason == "" {
+			reason, detail = a.containerInitSkipReason(ctx, *cand)
+		}
+		if reason == "" && a.reclaimInFlight(cand.Key) {
+			reason = "reclaim_in_progress"
+		}
+		if reason != "" {
+			a.log.Info(map[string]any{
+				"msg":          "reclaim candidate skipped",
+				"node":         a.node,
+				"skip_reason":  reason,
+				"skip_detail":  detail,
+				"policy":       cand.Policy.Name,
+				"idle_minutes": int(cand.IdleFor.Minutes()),
+				"gpu_indexes":  gpus,
+				"gpu_devices":  devices,
+				"pids":         pids,
+				"pod_uid":      cand.Key.UID,
+				"pod_ns":       cand.Key.Namespace,
+				"pod_name":     cand.Key.Name,
+				"container_id": cand.Key.ContainerID,
+			})
+			a.metrics.IncReclaim("skipped", reason)
+			if retrySkips[reason] {
+				a.tracker.Unreport(cand.Key)
+			}
+			continue
+		}
+
 		// FR-4: immediate validation to avoid edge mis-kill.
-		valid, reason, vErr := a.validateCandidate(ctx, *cand)
+		valid, reason, before, vErr := a.validateCandidate(ctx, *cand)
 		if vErr != nil {
 			a.log.Warn(map[string]any{"msg": "candidate validation error", "node": a.node, "error": vErr.Error()})
+			a.tracker.Unreport(cand.Key)
 			continue
 		}
 		if !valid {
@@ -184,41 +625,494 @@ func (a *Agent) tick(ctx context.Context) error {
 			continue
 		}
 
-		// M1: dry-run only (no signals).
-		action := "dry_run"
-		if !a.cfg.DryRun {
-			action = "dry_run_enforced_m1"
-		}
-		a.log.Info(map[string]any{
-			"msg":          "reclaim candidate (dry-run)",
-			"node":         a.node,
-			"action":       action,
-			"dry_run_cfg":  a.cfg.DryRun,
-			"idle_minutes": int(cand.IdleFor.Minutes()),
-			"util_samples": cand.Evidence.UtilSamples,
-			"gpu_indexes":  gpus,
-			"pids":         pids,
-			"cmdlines":     cand.Evidence.Cmdlines,
-			"pod_uid":      cand.Key.UID,
-			"pod_ns":       cand.Key.Namespace,
-			"pod_name":     cand.Key.Name,
-			"container_id": cand.Key.ContainerID,
-		})
+		enforce := a.isEnforcing()
+		if a.cfg.DryRun || !enforce {
+			a.log.Info(map[string]any{
+				"msg":          "reclaim candidate (dry-run)",
+				"node":         a.node,
+				"action":       "dry_run",
+				"mode":         modeName(enforce),
+				"policy":       cand.Policy.Name,
+				"idle_minutes": int(cand.IdleFor.Minutes()),
+				"util_samples": cand.Evidence.UtilSamples,
+				"util_signal":  cand.Evidence.Signal,
+				"gpu_indexes":  gpus,
+				"gpu_devices":  devices,
+				"pids":         pids,
+				"cmdlines":     cand.Evidence.Cmdlines,
+				"pod_uid":      cand.Key.UID,
+				"pod_ns":       cand.Key.Namespace,
+				"pod_name":     cand.Key.Name,
+				"container_id": cand.Key.ContainerID,
+			})
+			a.metrics.IncReclaim("dry_run", "idle")
+			a.podEvent(ctx, cand.Key, "GPUIdleReclaimCandidate",
+				fmt.Sprintf("%s; dry-run, no signal sent", describeCandidate(*cand)))
+			continue
+		}
+
+		a.podEvent(ctx, cand.Key, "GPUIdleReclaimPending",
+			fmt.Sprintf("%s; sending SIGTERM, SIGKILL after %ds", describeCandidate(*cand), a.cfg.TermGraceSeconds))
+
+		a.startReclaim(ctx, reclaimJob{cand: *cand, before: before, exec: a.reclaim, verify: a.verify, allowlist: a.allowlist})
+	}
+
+	a.metrics.SetIdleCandidates(a.tracker.ReportedCount(now))
+	if allocs != nil {
+		a.reportMismatches(mismatched)
+		if attribFail == 0 {
+			// Otherwise a pod whose PIDs failed attribution would look unused.
+			a.reportUnusedAllocations(snap, pods, allocs)
+		}
 	}
 
 	// Keep state bounded.
-	a.tracker.GC(now, 2*time.Hour)
+	a.tracker.GC(now, stateMaxAge)
+	a.saveState(now)
 	return nil
 }
 
-func (a *Agent) validateCandidate(ctx context.Context, cand idle.Candidate) (bool, string, error) {
-	snap, err := a.sampler.Sample(ctx)
+// policyFor resolves the idle policy tier for the pod's namespace; ok is
+// false when that depends on namespace labels the agent does not have.
+func (a *Agent) policyFor(k idle.PodKey) (idle.Policy, bool) {
+	var labels map[string]string
+	if a.policy.NeedsNamespaceLabels() && a.nsSrc != nil && k.Namespace != "" && a.nsSrc.HasSynced() {
+		labels, _ = a.nsSrc.NamespaceLabels(k.Namespace)
+	}
+	return a.policy.Resolve(k.Namespace, labels)
+}
+
+// retrySkips are skip reasons caused by metadata the agent could not read
+// yet rather than a decision about the pod, e.g. an informer that has not
+// synced after a restart. The candidate is re-armed so a later tick decides.
+var retrySkips = map[string]bool{
+	"pod_cache_not_synced": true,
+	"pod_lookup_failed":    true,
+	// The namespace's tier is not known yet, so the idle window may be
+	// longer than the default policy's.
+	"namespace_labels_unknown": true,
+	"pod_namespace_unknown":    true,
+}
+
+// skipReason returns why a candidate must not be reclaimed, or "" if it may.
+// detail names the rule that matched so the decision can be audited.
+// When something needed for a rule cannot be read we do not act: a
+// protection or opt-out we failed to see must never turn into a kill.
+func (a *Agent) skipReason(ctx context.Context, cand idle.Candidate) (reason, detail string) {
+	k := cand.Key
+	if rule, ok := a.protect.NamespaceRule(k.Namespace); ok && k.Namespace != "" {
+		return "protected_namespace", rule
+	}
+
+	if a.pods == nil {
+		if a.protect.HasNamespaces() && k.Namespace == "" {
+			return "pod_namespace_unknown", ""
+		}
+		if a.protect.HasPodSelectors() {
+			return "pod_labels_unknown", ""
+		}
+		// The pod may carry an opt-out annotation we cannot see.
+		return "pod_annotation_unknown", ""
+	}
+
+	meta, reason := a.lookupPod(ctx, k)
+	if reason != "" {
+		return reason, ""
+	}
+	if rule, ok := a.protect.NamespaceRule(meta.Namespace); ok {
+		return "protected_namespace", rule
+	}
+	if rule, ok := a.protect.PodLabelRule(meta.Labels); ok {
+		return "protected_pod_labels", rule
+	}
+	return a.podEnabledSkipReason(meta), ""
+}
+
+func (a *Agent) lookupPod(ctx context.Context, k idle.PodKey) (kube.ObjectMeta, string) {
+	if !a.pods.HasSynced() {
+		return kube.ObjectMeta{}, "pod_cache_not_synced"
+	}
+	lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	meta, ok, err := a.pods.Lookup(lctx, k.UID, k.Namespace, k.Name)
+	cancel()
+	if err != nil {
+		a.log.Warn(map[string]any{"msg": "pod lookup failed", "node": a.node, "pod_uid": k.UID, "pod_ns": k.Namespace, "pod_name": k.Name, "error": err.Error()})
+		return kube.ObjectMeta{}, "pod_lookup_failed"
+	}
+	if !ok {
+		return kube.ObjectMeta{}, "pod_not_found"
+	}
+	return meta, ""
+}
+
+// podEnabledSkipReason applies the pod-level annotation (PRD FR-10, AC-5).
+func (a *Agent) podEnabledSkipReason(meta kube.ObjectMeta) string {
+	v, present := meta.Annotations[a.cfg.PodEnabledAnnotationKey]
+	if !present {
+		if !a.cfg.PodEnabledDefault {
+			return "pod_disabled_by_default"
+		}
+		return ""
+	}
+	enabled, err := strconv.ParseBool(strings.TrimSpace(v))
 	if err != nil {
-		return false, "resample_failed", err
+		return "pod_annotation_invalid"
+	}
+	if !enabled {
+		return "pod_opted_out"
+	}
+	return ""
+}
+
+// reclaimJob is one candidate's reclaim. It carries the executor, verifier
+// and allowlist of the config it started under, since a reload replaces
+// them on the Run goroutine while the reclaim runs.
+type reclaimJob struct {
+	cand idle.Candidate
+	// before is the validation snapshot, used to verify the release.
+	before    sampling.Snapshot
+	exec      *reclaim.Executor
+	verify    *reclaim.Verifier
+	allowlist *regexp.Regexp
+}
+
+// startReclaim runs job on its own goroutine. Grace periods, retries and
+// verification can take minutes, during which every other pod must still
+// be sampled on schedule.
+func (a *Agent) startReclaim(ctx context.Context, job reclaimJob) {
+	ks := podKeyString(job.cand.Key)
+	a.inflightMu.Lock()
+	a.inflight[ks] = true
+	a.inflightMu.Unlock()
+
+	a.reclaims.Add(1)
+	go func() {
+		defer a.reclaims.Done()
+		defer func() {
+			a.inflightMu.Lock()
+			delete(a.inflight, ks)
+			a.inflightMu.Unlock()
+		}()
+		a.reclaimCandidate(ctx, job)
+	}()
+}
+
+func (a *Agent) reclaimInFlight(k idle.PodKey) bool {
+	a.inflightMu.Lock()
+	defer a.inflightMu.Unlock()
+	return a.inflight[podKeyString(k)]
+}
+
+// serialSampler serializes Sample calls, so a reclaim verifying in the
+// background never samples concurrently with tick.
+type serialSampler struct {
+	sampling.Sampler
+	mu sync.Mutex
+}
+
+func (s *serialSampler) Sample(ctx context.Context) (sampling.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Sampler.Sample(ctx)
+}
+
+// reclaimCandidate signals the candidate's GPU processes. PIDs are
+// re-attributed first, and again before every signal round of the executor,
+// so a PID recycled by another pod's process since the sample or during the
+// grace period is never hit.
+func (a *Agent) reclaimCandidate(ctx context.Context, job reclaimJob) {
+	// Told apart from the ticks they interleave with in a recording.
+	ctx = recording.WithKind(ctx, recording.KindReclaim)
+	cand := job.cand
+	pids, dropped := a.confirmOwnership(ctx, cand)
+	fields := map[string]any{
+		"node":         a.node,
+		"action":       "reclaim",
+		"policy":       cand.Policy.Name,
+		"idle_minutes": int(cand.IdleFor.Minutes()),
+		"util_samples": cand.Evidence.UtilSamples,
+		"util_signal":  cand.Evidence.Signal,
+		"gpu_indexes":  cand.Evidence.GPUs,
+		"gpu_devices":  cand.Evidence.Devices,
+		"pids":         pids,
+		"cmdlines":     cand.Evidence.Cmdlines,
+		"pod_uid":      cand.Key.UID,
+		"pod_ns":       cand.Key.Namespace,
+		"pod_name":     cand.Key.Name,
+		"container_id": cand.Key.ContainerID,
+	}
+	if len(dropped) > 0 {
+		fields["pids_not_owned"] = dropped
+	}
+	if len(pids) == 0 {
+		fields["msg"] = "reclaim skipped: no owned pids left"
+		fields["result"] = "skipped"
+		a.log.Info(fields)
+		return
+	}
+	targets := a.reclaimTargets(ctx, cand, pids, job.allowlist)
+	fields["reclaim_target"] = cand.Policy.ReclaimTarget
+	fields["target_pids"] = targets
+
+	res := job.exec.Reclaim(ctx, targets, func(pid int) bool { return a.ownsPID(ctx, cand.Key, pid) })
+	signals := make([]map[string]any, 0, len(res.Signals))
+	for _, ev := range res.Signals {
+		if ev.Error == "" {
+			a.metrics.IncKill(ev.Signal)
+		}
+		sig := map[string]any{"pid": ev.PID, "signal": ev.Signal, "attempt": ev.Attempt, "ts": ev.At.UTC().Format(time.RFC3339Nano)}
+		if ev.Error != "" {
+			sig["error"] = ev.Error
+		}
+		signals = append(signals, sig)
+	}
+	fields["signals"] = signals
+	fields["attempts"] = res.Attempts
+	if len(res.NotOwned) > 0 {
+		fields["pids_reused"] = res.NotOwned
+	}
+
+	if !res.Success() {
+		fields["msg"] = "reclaim failed"
+		fields["result"] = "fail"
+		fields["remaining_pids"] = res.Remaining
+		if res.Err != nil {
+			fields["error"] = res.Err.Error()
+		}
+		a.log.Warn(fields)
+		a.metrics.IncReclaimFailure("signal")
+		a.podEvent(ctx, cand.Key, "GPUReclaimFailed",
+			fmt.Sprintf("%s; PIDs %v still alive after %d attempt(s)", describeCandidate(cand), res.Remaining, res.Attempts))
+		return
+	}
+
+	// FR-9: confirm the PIDs left the GPU and their memory was released.
+	ver := job.verify.Verify(recording.WithKind(ctx, recording.KindVerify), job.before, pids)
+	fields["verify"] = string(ver.Status)
+	fields["verify_polls"] = ver.Polls
+	fields["expected_freed_bytes"] = ver.ExpectedFreedBytes
+	fields["observed_freed_bytes"] = ver.ObservedFreedBytes
+	if len(ver.StillPresent) > 0 {
+		fields["still_present_pids"] = ver.StillPresent
 	}
-	gpuIdxSet := map[int]struct{}{}
-	for _, gi := range cand.Evidence.GPUs {
-		gpuIdxSet[gi] = struct{}{}
+	if ver.Err != nil {
+		fields["verify_error"] = ver.Err.Error()
+	}
+	if ver.Status != reclaim.Verified {
+		fields["msg"] = "reclaim signalled but release not verified"
+		fields["result"] = "fail"
+		a.log.Warn(fields)
+		a.metrics.IncReclaimFailure(string(ver.Status))
+		a.podEvent(ctx, cand.Key, "GPUReclaimFailed",
+			fmt.Sprintf("%s; signalled PIDs %v but release is %s", describeCandidate(cand), pids, ver.Status))
+		return
+	}
+	fields["msg"] = "reclaim succeeded"
+	fields["result"] = "success"
+	a.log.Info(fields)
+	a.metrics.IncReclaim("success", "idle")
+	a.podEvent(ctx, cand.Key, "GPUReclaimed",
+		fmt.Sprintf("%s; GPU processes %v were terminated and their GPU memory released", describeCandidate(cand), pids))
+}
+
+// podEvent posts a Warning Event on the candidate's pod. Failures are logged
+// and never block reclaim.
+func (a *Agent) podEvent(ctx context.Context, k idle.PodKey, reason, message string) {
+	if a.events == nil {
+		return
+	}
+	if k.Namespace == "" || k.Name == "" {
+		a.log.Warn(map[string]any{"msg": "pod event skipped: pod name unknown", "node": a.node, "reason": reason, "pod_uid": k.UID, "container_id": k.ContainerID})
+		return
+	}
+	evCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := a.events.PodWarning(evCtx, k.Namespace, k.Name, k.UID, reason, message); err != nil {
+		a.log.Warn(map[string]any{"msg": "pod event failed", "node": a.node, "reason": reason, "pod_ns": k.Namespace, "pod_name": k.Name, "error": err.Error()})
+	}
+}
+
+func describeCandidate(c idle.Candidate) string {
+	var gpus any = c.Evidence.GPUs
+	if len(c.Evidence.Devices) > 0 {
+		gpus = c.Evidence.Devices
+	}
+	return fmt.Sprintf("GPU idle for %dm on GPU(s) %v (PIDs %v)", int(c.IdleFor.Minutes()), gpus, c.Evidence.PIDs)
+}
+
+// confirmOwnership re-resolves each candidate PID and keeps only those that
+// still belong to the candidate's pod (PRD FR-6: act only on unambiguous
+// attribution).
+func (a *Agent) confirmOwnership(ctx context.Context, cand idle.Candidate) (owned []int, dropped []int) {
+	for _, pid := range cand.Evidence.PIDs {
+		if a.ownsPID(ctx, cand.Key, pid) {
+			owned = append(owned, pid)
+		} else {
+			dropped = append(dropped, pid)
+		}
+	}
+	return owned, dropped
+}
+
+// ownsPID reports whether pid currently attributes to the pod k.
+func (a *Agent) ownsPID(ctx context.Context, k idle.PodKey, pid int) bool {
+	_, ok := a.resolveOwned(ctx, k, pid)
+	return ok
+}
+
+// resolveOwned attributes pid and reports whether it belongs to k. Pods are
+// tracked by UID across all their containers (see podKeyString), so
+// k.ContainerID is only whichever container was seen first and is compared
+// only when the pod UID is unknown.
+func (a *Agent) resolveOwned(ctx context.Context, k idle.PodKey, pid int) (attribution.Attribution, bool) {
+	attrCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	attr, err := a.attrib.ResolvePID(attrCtx, pid)
+	cancel()
+	if err != nil {
+		return attr, false
+	}
+	if k.UID != "" {
+		return attr, attr.PodUID == k.UID
+	}
+	return attr, k.ContainerID != "" && attr.ContainerID == k.ContainerID
+}
+
+// containerInitSkipReason refuses a candidate whose GPU process is its
+// container's init (its parent is outside the container, see
+// reclaimTargets): signalling it stops the container and the pod restarts
+// instead of giving the GPU back (AC-3). Without the process tree we cannot
+// tell, so we do not act.
+func (a *Agent) containerInitSkipReason(ctx context.Context, cand idle.Candidate) (reason, detail string) {
+	if a.procs == nil {
+		return "", ""
+	}
+	tree, err := a.procs.ProcessTree()
+	if err != nil {
+		return "process_tree_unavailable", err.Error()
+	}
+	for _, pid := range cand.Evidence.PIDs {
+		p, ok := tree.Get(pid)
+		if !ok {
+			// Exited since the sample; the executor finds it gone.
+			continue
+		}
+		attr, owned := a.resolveOwned(ctx, cand.Key, pid)
+		if !owned || attr.ContainerID == "" {
+			continue
+		}
+		if p.PPID > 0 {
+			attrCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			parent, err := a.attrib.ResolvePID(attrCtx, p.PPID)
+			cancel()
+			if err == nil && parent.ContainerID == attr.ContainerID {
+				continue
+			}
+		}
+		return "gpu_pid_is_container_init", fmt.Sprintf("pid %d", pid)
+	}
+	return "", ""
+}
+
+// reclaimTargets widens the GPU PIDs to their process group or process tree
+// per the candidate's policy, so a supervisor (torchrun, a Jupyter kernel
+// manager) cannot respawn what was killed. Only processes of the GPU PID's
+// own container are added, never allowlisted ones and never the container's
+// init (the process whose parent is outside it): killing init stops the
+// whole container and the pod restarts instead of giving the GPU back.
+// Candidates whose GPU PID is init are skipped before reaching here (see
+// containerInitSkipReason). A GPU PID whose container is unknown is
+// signalled alone.
+func (a *Agent) reclaimTargets(ctx context.Context, cand idle.Candidate, pids []int, allowlist *regexp.Regexp) []int {
+	target := cand.Policy.ReclaimTarget
+	if target == config.ReclaimTargetPID || target == "" || a.procs == nil {
+		return pids
+	}
+	tree, err := a.procs.ProcessTree()
+	if err != nil {
+		a.log.Warn(map[string]any{"msg": "process tree unavailable; signalling gpu pids only", "node": a.node, "error": err.Error()})
+		return pids
+	}
+	type owned struct {
+		cid     string // container ID; "" when not the candidate's pod
+		allowed bool
+	}
+	resolved := map[int]owned{}
+	resolve := func(pid int) owned {
+		o, ok := resolved[pid]
+		if !ok {
+			attr, in := a.resolveOwned(ctx, cand.Key, pid)
+			if in {
+				o = owned{cid: attr.ContainerID, allowed: !(attr.Cmdline != "" && allowlist.MatchString(attr.Cmdline))}
+			}
+			resolved[pid] = o
+		}
+		return o
+	}
+
+	seen := map[int]bool{}
+	var out []int
+	for _, pid := range pids {
+		cid := resolve(pid).cid
+		sameContainer := func(p int) bool {
+			o := resolve(p)
+			return o.allowed && o.cid == cid
+		}
+		isInit := func(p int) bool {
+			proc, ok := tree.Get(p)
+			return !ok || proc.PPID <= 0 || resolve(proc.PPID).cid != cid
+		}
+		var members []int
+		if cid != "" {
+			switch target {
+			case config.ReclaimTargetProcessGroup:
+				if p, ok := tree.Get(pid); ok {
+					members = tree.Group(p.PGID)
+				}
+			case config.ReclaimTargetSubtree:
+				// Climb to the outermost ancestor below the container's
+				// init; the runtime shim above init is not in the container.
+				root := pid
+				for _, anc := range tree.Ancestors(pid) {
+					if !sameContainer(anc) || isInit(anc) {
+						break
+					}
+					root = anc
+				}
+				members = tree.Subtree(root)
+			}
+		}
+		for _, m := range append(members, pid) {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			if m == pid || sameContainer(m) && !isInit(m) {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+func (a *Agent) validateCandidate(ctx context.Context, cand idle.Candidate) (bool, string, sampling.Snapshot, error) {
+	snap, err := a.sampler.Sample(recording.WithKind(ctx, recording.KindValidate))
+	if err != nil {
+		return false, "resample_failed", snap, err
+	}
+	if bad := snap.Unhealthy(); len(bad) > 0 {
+		// The candidate's processes may sit on the unreadable device, so
+		// what we see of it is incomplete.
+		return false, fmt.Sprintf("gpu_%s_unhealthy", bad[0].ID()), snap, nil
+	}
+	devSet := map[string]struct{}{}
+	for _, id := range cand.Evidence.Devices {
+		devSet[id] = struct{}{}
+	}
+	if len(devSet) == 0 {
+		// Evidence restored from a checkpoint written before MIG support.
+		for _, gi := range cand.Evidence.GPUs {
+			devSet[strconv.Itoa(gi)] = struct{}{}
+		}
 	}
 	pidSet := map[int]struct{}{}
 	for _, pid := range cand.Evidence.PIDs {
@@ -227,24 +1121,48 @@ func (a *Agent) validateCandidate(ctx context.Context, cand idle.Candidate) (boo
 
 	seenAnyPID := false
 	for _, g := range snap.GPUs {
-		if _, ok := gpuIdxSet[g.Index]; !ok {
+		if _, ok := devSet[g.ID()]; !ok {
 			continue
 		}
-		if int(g.UtilGPU) >= a.cfg.GPUUtilThresholdPct {
-			return false, fmt.Sprintf("gpu_%d_util_not_idle", g.Index), nil
-		}
+		u := newUsage(g)
 		for _, p := range g.ComputeProcs {
 			if _, ok := pidSet[p.PID]; ok {
 				seenAnyPID = true
-				break
+				addProcUsage(u, p)
 			}
 		}
+		busy, ok := u.BusyPct()
+		if !ok {
+			return false, fmt.Sprintf("gpu_%s_util_unknown", g.ID()), snap, nil
+		}
+		if busy >= float64(cand.Policy.UtilThresholdPct) {
+			return false, fmt.Sprintf("gpu_%s_util_not_idle", g.ID()), snap, nil
+		}
 	}
 
 	if !seenAnyPID {
-		return false, "pids_gone", nil
+		return false, "pids_gone", snap, nil
+	}
+	return true, "ok", snap, nil
+}
+
+func newUsage(g sampling.GPUSnapshot) *idle.GPUUsage {
+	busy, ok := g.BusyPct()
+	return &idle.GPUUsage{GPU: g.Index, Device: g.ID(), CardBusyPct: busy, CardKnown: ok, ProcKnown: true}
+}
+
+// addProcUsage folds one of the pod's processes on the GPU into u. A single
+// process without a per-process reading makes the whole GPU fall back to
+// card-level utilization.
+func addProcUsage(u *idle.GPUUsage, p sampling.GPUProcess) {
+	if !p.HasUtil {
+		u.ProcKnown = false
+		return
+	}
+	u.ProcBusyPct += float64(p.SMUtil)
+	if u.ProcBusyPct > 100 {
+		u.ProcBusyPct = 100
 	}
-	return true, "ok", nil
 }
 
 func podKeyString(k idle.PodKey) string {
@@ -272,3 +1190,64 @@ func limitStrings(in []string, max int) []string {
 	}
 	return in[:max]
 }
+
+// reportPIDNamespace logs once when GPU PIDs cannot be attributed because
+// the agent does not share the host PID namespace, and once when PIDs
+// resolve again, instead of a warning per PID per tick.
+func (a *Agent) reportPIDNamespace(err error, resolved int) {
+	if err == nil && resolved == 0 {
+		// No GPU processes this tick; nothing learned.
+		return
+	}
+	if err != nil && !a.pidnsFailing {
+		a.log.Error(map[string]any{
+			"msg":   "gpu pids cannot be attributed: pid namespace mismatch; run the agent with hostPID: true",
+			"node":  a.node,
+			"error": err.Error(),
+		})
+	}
+	if err == nil && a.pidnsFailing {
+		a.log.Info(map[string]any{"msg": "gpu pids attributable again", "node": a.node})
+	}
+	a.pidnsFailing = err != nil
+}
+
+// logHealth warns when a device becomes unreadable and notes its recovery.
+func (a *Agent) logHealth(snap sampling.Snapshot) {
+	cur := map[string]string{}
+	for _, g := range snap.Unhealthy() {
+		cur[g.ID()] = g.Error
+		if _, seen := a.unhealthy[g.ID()]; !seen {
+			a.log.Warn(map[string]any{"msg": "gpu unhealthy", "node": a.node, "gpu": g.ID(), "uuid": g.UUID, "error": g.Error})
+		}
+	}
+	for id := range a.unhealthy {
+		if _, still := cur[id]; !still {
+			a.log.Info(map[string]any{"msg": "gpu recovered", "node": a.node, "gpu": id})
+		}
+	}
+	a.unhealthy = cur
+}
+
+// logMIGUtil warns when processes run on a MIG slice whose utilization is
+// unknown. NVML and nvidia-smi cannot read it, so pods there never count as
+// idle and are never reclaimed; only the DCGM sampler judges MIG slices.
+func (a *Agent) logMIGUtil(snap sampling.Snapshot) {
+	cur := map[string]bool{}
+	for _, g := range snap.GPUs {
+		if _, known := g.BusyPct(); g.MIG == nil || known || !g.Healthy() || len(g.ComputeProcs) == 0 {
+			continue
+		}
+		cur[g.ID()] = true
+		if !a.migUnknown[g.ID()] {
+			a.log.Warn(map[string]any{
+				"msg":     "mig slice utilization unknown: pods on it are never reclaimed; use --sampler=dcgm to judge mig slices",
+				"node":    a.node,
+				"reason":  "mig_util_unknown",
+				"gpu":     g.ID(),
+				"sampler": a.sampler.Name(),
+			})
+		}
+	}
+	a.migUnknown = cur
+}
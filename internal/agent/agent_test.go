@@ -0,0 +1,804 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/clock"
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/idle"
+	"gpu-reclaimer-agent/internal/kube"
+	"gpu-reclaimer-agent/internal/logging"
+	"gpu-reclaimer-agent/internal/metrics"
+	"gpu-reclaimer-agent/internal/podresources"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+const (
+	testPodUID = "2b1c7d4e-6f5a-4c3b-9a8d-1e2f3a4b5c6d"
+	testCID    = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	testPID    = 4242
+	gib        = 1 << 30
+)
+
+// fakeHost is a synthetic node: a procfs tree under a temp dir, one GPU whose
+// process list follows that tree, and a kill(2) that removes processes.
+type fakeHost struct {
+	t        *testing.T
+	procRoot string
+
+	mu      sync.Mutex
+	util    uint32
+	gpuPIDs map[int]uint64 // pid -> GPU memory
+	signals []string
+	// PIDs that survive SIGTERM and only exit on SIGKILL.
+	ignoreTERM map[int]bool
+	// Per-process SM utilization; PIDs not in it have no reading.
+	procUtil map[int]uint32
+	// onTERM, when set, runs instead of the exit a SIGTERM causes.
+	onTERM func(pid int)
+	// device, when set, rewrites the GPU entry of each sample, e.g. into
+	// a MIG slice as a given sampler reports it.
+	device func(g *sampling.GPUSnapshot)
+	closed int
+}
+
+func newFakeHost(t *testing.T) *fakeHost {
+	return &fakeHost{t: t, procRoot: t.TempDir(), gpuPIDs: map[int]uint64{}, ignoreTERM: map[int]bool{}, procUtil: map[int]uint32{}}
+}
+
+// containerCgroup is the cgroup v2 path of a container of the test pod.
+func containerCgroup(cid string) string {
+	return "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" +
+		strings.ReplaceAll(testPodUID, "-", "_") + ".slice/cri-containerd-" + cid + ".scope"
+}
+
+// addProc creates /proc/<pid> for a process of the test pod's container,
+// leading its own process group.
+func (h *fakeHost) addProc(pid, ppid int, cmdline string, gpuBytes uint64) {
+	h.writeProc(pid, ppid, pid, containerCgroup(testCID), cmdline, gpuBytes)
+}
+
+// writeProc creates or replaces /proc/<pid>.
+func (h *fakeHost) writeProc(pid, ppid, pgid int, cgroup, cmdline string, gpuBytes uint64) {
+	dir := filepath.Join(h.procRoot, fmt.Sprint(pid))
+	files := map[string]string{
+		"cgroup":  "0::" + cgroup + "\n",
+		"cmdline": strings.ReplaceAll(cmdline, " ", "\x00") + "\x00",
+		"stat":    fmt.Sprintf("%d (%s) S %d %d %d 0 -1\n", pid, strings.Fields(cmdline)[0], ppid, pgid, pgid),
+		"status":  fmt.Sprintf("Name:\t%s\nNSpid:\t%d\n", strings.Fields(cmdline)[0], pid),
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		h.t.Fatal(err)
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if gpuBytes > 0 {
+		h.gpuPIDs[pid] = gpuBytes
+	}
+}
+
+func (h *fakeHost) setUtil(pct uint32) {
+	h.mu.Lock()
+	h.util = pct
+	h.mu.Unlock()
+}
+
+func (h *fakeHost) signal(pid int, sig syscall.Signal) error {
+	dir := filepath.Join(h.procRoot, fmt.Sprint(pid))
+	if _, err := os.Stat(dir); err != nil {
+		return syscall.ESRCH
+	}
+	if sig == 0 {
+		return nil
+	}
+	h.mu.Lock()
+	h.signals = append(h.signals, fmt.Sprintf("%d:%s", pid, sig))
+	if sig == syscall.SIGTERM && h.ignoreTERM[pid] {
+		h.mu.Unlock()
+		return nil
+	}
+	if sig == syscall.SIGTERM && h.onTERM != nil {
+		h.mu.Unlock()
+		h.onTERM(pid)
+		return nil
+	}
+	delete(h.gpuPIDs, pid)
+	h.mu.Unlock()
+	// Other processes exit on the first signal.
+	return os.RemoveAll(dir)
+}
+
+func (h *fakeHost) sent() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.signals...)
+}
+
+func (h *fakeHost) Name() string { return "fake" }
+
+func (h *fakeHost) Close() error {
+	h.mu.Lock()
+	h.closed++
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *fakeHost) Sample(context.Context) (sampling.Snapshot, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g := sampling.GPUSnapshot{Index: 0, UUID: "GPU-0", UtilGPU: h.util, MemTotalBytes: 80 * gib}
+	for pid, b := range h.gpuPIDs {
+		sm, ok := h.procUtil[pid]
+		g.ComputeProcs = append(g.ComputeProcs, sampling.GPUProcess{PID: pid, UsedBytes: b, HasUtil: ok, SMUtil: sm})
+		g.MemUsedBytes += b
+	}
+	if h.device != nil {
+		h.device(&g)
+	}
+	return sampling.Snapshot{GPUs: []sampling.GPUSnapshot{g}}, nil
+}
+
+// fakePods serves the test pod's metadata as a synced informer would.
+type fakePods struct{ meta kube.ObjectMeta }
+
+func (p fakePods) HasSynced() bool { return true }
+
+func (p fakePods) PodByUID(uid string) (kube.ObjectMeta, bool) {
+	return p.meta, uid == p.meta.UID
+}
+
+func (p fakePods) Lookup(_ context.Context, uid, _, _ string) (kube.ObjectMeta, bool, error) {
+	m, ok := p.PodByUID(uid)
+	return m, ok, nil
+}
+
+type recordedEvents struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *recordedEvents) PodWarning(_ context.Context, _, _, _, reason, _ string) error {
+	r.mu.Lock()
+	r.reasons = append(r.reasons, reason)
+	r.mu.Unlock()
+	return nil
+}
+
+type scenario struct {
+	host   *fakeHost
+	clock  *clock.Fake
+	agent  *Agent
+	events *recordedEvents
+	logs   *bytes.Buffer
+	// Command line the config was loaded from.
+	args []string
+}
+
+func newScenario(t *testing.T, annotations map[string]string, extraArgs ...string) *scenario {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	args := append([]string{
+		"--idle-minutes=30", "--sample-interval=1m", "--consecutive-idle-samples=30",
+		"--gpu-util-threshold=1", "--dry-run=false", "--metrics-addr=",
+		"--cri-endpoint=unix://" + filepath.Join(t.TempDir(), "no-cri.sock"),
+	}, extraArgs...)
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	s := &scenario{
+		host:   newFakeHost(t),
+		clock:  clock.NewFake(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)),
+		events: &recordedEvents{},
+		logs:   &bytes.Buffer{},
+		args:   args,
+	}
+	s.host.addProc(1, 0, "/pause", 0)
+	s.host.addProc(100, 1, "bash -c python train.py", 0)
+	s.host.addProc(testPID, 100, "python train.py", 10*gib)
+
+	s.agent, err = New(Options{
+		Config:   cfg,
+		NodeName: "node-a",
+		Logger:   logging.NewJSONLogger(s.logs),
+		Events:   s.events,
+		Pods: fakePods{kube.ObjectMeta{
+			UID: testPodUID, Namespace: "team-a", Name: "train-0", Annotations: annotations,
+		}},
+		Clock:    s.clock,
+		Sampler:  s.host,
+		ProcRoot: s.host.procRoot,
+		Signal:   s.host.signal,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+// logged returns the log entries with msg, in order.
+func (s *scenario) logged(msg string) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(s.logs.String(), "\n") {
+		var e map[string]any
+		if json.Unmarshal([]byte(line), &e) == nil && e["msg"] == msg {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// run ticks once per sample interval for minutes samples, calling at (if
+// set) before each tick with the minutes elapsed since the start. Reclaims
+// a tick starts are waited for before the next one.
+func (s *scenario) run(t *testing.T, minutes int, at func(minute int)) {
+	t.Helper()
+	for m := 0; m < minutes; m++ {
+		if at != nil {
+			at(m)
+		}
+		if err := s.agent.tick(context.Background()); err != nil {
+			t.Fatalf("tick at minute %d: %v", m, err)
+		}
+		s.agent.reclaims.Wait()
+		s.clock.Advance(time.Minute)
+	}
+}
+
+func TestIdlePodReclaimedAfterThirtyMinutes(t *testing.T) {
+	s := newScenario(t, nil)
+
+	// Samples at minutes 0..29 cover 29 idle minutes: not yet.
+	s.run(t, 30, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled before 30 idle minutes: %v", got)
+	}
+
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d only", got, testPID)
+	}
+	if !strings.Contains(s.logs.String(), `"msg":"reclaim succeeded"`) || !strings.Contains(s.logs.String(), `"verify":"verified"`) {
+		t.Errorf("no verified reclaim in log:\n%s", s.logs)
+	}
+	want := []string{"GPUIdleReclaimPending", "GPUReclaimed"}
+	if fmt.Sprint(s.events.reasons) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", s.events.reasons, want)
+	}
+
+	// The pod's parent processes are untouched with the default target.
+	for _, pid := range []int{1, 100} {
+		if _, err := os.Stat(filepath.Join(s.host.procRoot, fmt.Sprint(pid))); err != nil {
+			t.Errorf("pid %d was removed", pid)
+		}
+	}
+}
+
+func TestActivityResetsIdleWindow(t *testing.T) {
+	s := newScenario(t, nil)
+	s.run(t, 31, func(minute int) {
+		if minute == 20 {
+			s.host.setUtil(60)
+		} else {
+			s.host.setUtil(0)
+		}
+	})
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled a pod that was busy at minute 20: %v", got)
+	}
+	// Idle again from minute 21: 30 idle minutes end at minute 51.
+	s.run(t, 20, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled at minute 50: %v", got)
+	}
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 {
+		t.Fatalf("signals = %v, want one reclaim at minute 51", got)
+	}
+}
+
+// addNeighbour puts a busy process of another pod on the test pod's GPU.
+func (h *fakeHost) addNeighbour(pid int, util uint32) {
+	const uid = "7c6b5a49-3827-4160-9f8e-7d6c5b4a3928"
+	cgroup := "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" +
+		strings.ReplaceAll(uid, "-", "_") + ".slice/cri-containerd-" + strings.Repeat("ab", 32) + ".scope"
+	h.writeProc(pid, 1, pid, cgroup, "python infer.py", 20*gib)
+	h.mu.Lock()
+	h.procUtil[pid] = util
+	h.mu.Unlock()
+}
+
+func TestBusyNeighbourDoesNotKeepIdlePodAlive(t *testing.T) {
+	s := newScenario(t, nil)
+	// A time-sliced GPU: the neighbour keeps the card at 90%, the pod's
+	// own process does nothing.
+	s.host.addNeighbour(5000, 90)
+	s.host.setUtil(90)
+	s.host.procUtil[testPID] = 0
+
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d only", got, testPID)
+	}
+	if rec := s.logged("reclaim succeeded"); len(rec) != 1 || rec[0]["util_signal"] != idle.SignalProcess {
+		t.Errorf("reclaims = %v, want one judged on the process signal", rec)
+	}
+}
+
+func TestUnknownProcessUtilFallsBackToCard(t *testing.T) {
+	s := newScenario(t, nil)
+	s.host.addNeighbour(5000, 90)
+	s.host.setUtil(90)
+	// The pod's process has no per-process reading: the busy card decides.
+	s.run(t, 45, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled a pod on a busy card without its own reading: %v", got)
+	}
+
+	// Once the card goes quiet the card-level signal finds the pod idle.
+	s.host.mu.Lock()
+	delete(s.host.gpuPIDs, 5000)
+	s.host.mu.Unlock()
+	s.host.setUtil(0)
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 1 {
+		t.Fatalf("signals = %v, want one reclaim on the card signal", got)
+	}
+	if rec := s.logged("reclaim succeeded"); len(rec) != 1 || rec[0]["util_signal"] != idle.SignalCard {
+		t.Errorf("reclaims = %v, want one judged on the card signal", rec)
+	}
+}
+
+func TestOptedOutPodNeverReclaimed(t *testing.T) {
+	s := newScenario(t, map[string]string{"gpu-reclaimer/enabled": "false"})
+	s.run(t, 45, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled an opted-out pod: %v", got)
+	}
+	if !strings.Contains(s.logs.String(), `"skip_reason":"pod_opted_out"`) {
+		t.Errorf("no pod_opted_out skip in log:\n%s", s.logs)
+	}
+}
+
+func TestProtectedPodsNeverSignalled(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		labels map[string]string
+		reason string
+	}{
+		{"namespace glob", []string{"--protected-namespaces=kube-system,team-*"}, nil, "protected_namespace"},
+		{"pod labels", []string{"--protected-pod-selectors=tier=online"}, map[string]string{"tier": "online"}, "protected_pod_labels"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newScenario(t, nil, c.args...)
+			s.agent.pods = fakePods{kube.ObjectMeta{UID: testPodUID, Namespace: "team-a", Name: "train-0", Labels: c.labels}}
+			s.run(t, 45, nil)
+			if got := s.host.sent(); len(got) != 0 {
+				t.Fatalf("signalled a protected pod: %v", got)
+			}
+			skips := s.logged("reclaim candidate skipped")
+			if len(skips) == 0 || skips[0]["skip_reason"] != c.reason {
+				t.Errorf("skips = %v, want %s", skips, c.reason)
+			}
+			if len(s.logged("reclaim candidate (dry-run)")) != 0 || len(s.events.reasons) != 0 {
+				t.Errorf("protected pod went past the skip: events %v\n%s", s.events.reasons, s.logs)
+			}
+		})
+	}
+}
+
+func TestPodWithoutAnnotationSourceNeverReclaimed(t *testing.T) {
+	// No protected namespaces, so the unknown namespace alone is no reason
+	// to skip.
+	s := newScenario(t, map[string]string{"gpu-reclaimer/enabled": "false"}, "--protected-namespaces=")
+	// The API server was unreachable at startup: no pod source at all.
+	s.agent.pods = nil
+	s.run(t, 45, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled a pod whose annotations could not be read: %v", got)
+	}
+	if !strings.Contains(s.logs.String(), `"skip_reason":"pod_annotation_unknown"`) {
+		t.Errorf("no pod_annotation_unknown skip in log:\n%s", s.logs)
+	}
+}
+
+// lateSyncPods is a pod cache that has not synced until synced is set.
+type lateSyncPods struct {
+	fakePods
+	synced bool
+}
+
+func (p *lateSyncPods) HasSynced() bool { return p.synced }
+
+func TestUnsyncedPodCacheDefersReclaim(t *testing.T) {
+	s := newScenario(t, nil)
+	pods := &lateSyncPods{fakePods: s.agent.pods.(fakePods)}
+	s.agent.pods = pods
+
+	// The pod becomes a candidate at minute 30, before the cache syncs.
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled before the pod cache synced: %v", got)
+	}
+	if !strings.Contains(s.logs.String(), `"skip_reason":"pod_cache_not_synced"`) {
+		t.Fatalf("no pod_cache_not_synced skip in log:\n%s", s.logs)
+	}
+
+	pods.synced = true
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d once the cache synced", got, testPID)
+	}
+}
+
+// lateSyncNamespaces is a namespace cache that has not synced until synced
+// is set.
+type lateSyncNamespaces struct {
+	labels map[string]map[string]string
+	synced bool
+}
+
+func (n *lateSyncNamespaces) HasSynced() bool { return n.synced }
+
+func (n *lateSyncNamespaces) NamespaceLabels(name string) (map[string]string, bool) {
+	l, ok := n.labels[name]
+	return l, ok
+}
+
+func TestUnsyncedNamespaceCacheDefersReclaim(t *testing.T) {
+	s := newScenario(t, nil, `--policies=[{"name":"research","namespaceSelector":"team=research","idleMinutes":120}]`)
+	nss := &lateSyncNamespaces{labels: map[string]map[string]string{"team-a": {"team": "infra"}}}
+	s.agent.nsSrc = nss
+
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled before the namespace tier was known: %v", got)
+	}
+	if !strings.Contains(s.logs.String(), `"skip_reason":"namespace_labels_unknown"`) {
+		t.Fatalf("no namespace_labels_unknown skip in log:\n%s", s.logs)
+	}
+
+	// team-a is not a research namespace: the default 30 minutes apply.
+	nss.synced = true
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d once the namespace cache synced", got, testPID)
+	}
+}
+
+func TestDryRunOnlyReports(t *testing.T) {
+	s := newScenario(t, nil, "--dry-run=true")
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("dry-run sent signals: %v", got)
+	}
+	if !strings.Contains(s.logs.String(), `"msg":"reclaim candidate (dry-run)"`) {
+		t.Errorf("no dry-run candidate in log:\n%s", s.logs)
+	}
+}
+
+func TestWidenedReclaimSparesContainerInit(t *testing.T) {
+	for _, target := range []string{"process-group", "subtree"} {
+		t.Run(target, func(t *testing.T) {
+			s := newScenario(t, nil, "--reclaim-target="+target)
+			h := s.host
+			// A real node: the shim (outside the pod) starts the pause
+			// container and the workload container's init, bash, whose
+			// children share its process group.
+			const sandboxCID = "9a8b7c6d5e4f30211203f4e5d6c7b8a99a8b7c6d5e4f30211203f4e5d6c7b8a9"
+			h.writeProc(50, 1, 50, "/system.slice/containerd.service", "containerd-shim-runc-v2 -namespace k8s.io", 0)
+			h.writeProc(60, 50, 60, containerCgroup(sandboxCID), "/pause", 0)
+			h.writeProc(100, 50, 100, containerCgroup(testCID), "bash -c torchrun train.py", 0)
+			h.writeProc(200, 100, 100, containerCgroup(testCID), "torchrun train.py", 0)
+			h.writeProc(testPID, 200, 100, containerCgroup(testCID), "python train.py", 10*gib)
+			h.writeProc(4243, testPID, 100, containerCgroup(testCID), "python -c worker", 0)
+
+			s.run(t, 31, nil)
+			got := map[int]bool{}
+			for _, sig := range h.sent() {
+				var pid int
+				fmt.Sscanf(sig, "%d:", &pid)
+				got[pid] = true
+			}
+			for _, pid := range []int{200, testPID, 4243} {
+				if !got[pid] {
+					t.Errorf("pid %d not signalled; signals = %v", pid, h.sent())
+				}
+			}
+			for _, pid := range []int{1, 50, 60, 100} {
+				if got[pid] {
+					t.Errorf("pid %d signalled; signals = %v", pid, h.sent())
+				}
+			}
+		})
+	}
+}
+
+func TestGPUProcessThatIsContainerInitIsNotSignalled(t *testing.T) {
+	for _, target := range []string{"pid", "process-group", "subtree"} {
+		t.Run(target, func(t *testing.T) {
+			s := newScenario(t, nil, "--reclaim-target="+target)
+			// The container's command is the training script itself: the
+			// shim starts it, so killing it restarts the container.
+			s.host.writeProc(50, 1, 50, "/system.slice/containerd.service", "containerd-shim-runc-v2 -namespace k8s.io", 0)
+			s.host.writeProc(testPID, 50, testPID, containerCgroup(testCID), "python train.py", 10*gib)
+
+			s.run(t, 31, nil)
+			if got := s.host.sent(); len(got) != 0 {
+				t.Fatalf("signalled the container's init: %v", got)
+			}
+			skips := s.logged("reclaim candidate skipped")
+			if len(skips) != 1 || skips[0]["skip_reason"] != "gpu_pid_is_container_init" || skips[0]["skip_detail"] != fmt.Sprintf("pid %d", testPID) {
+				t.Fatalf("skips = %v, want gpu_pid_is_container_init for pid %d", skips, testPID)
+			}
+			if len(s.events.reasons) != 0 {
+				t.Errorf("events = %v, want none", s.events.reasons)
+			}
+		})
+	}
+}
+
+func TestRespawningInitSupervisorIsSparedAndReportedUnverified(t *testing.T) {
+	s := newScenario(t, nil, "--reclaim-target=subtree", "--verify-timeout-seconds=1")
+	h := s.host
+	// A supervisor is the container's init and restarts its GPU child.
+	h.writeProc(50, 1, 50, "/system.slice/containerd.service", "containerd-shim-runc-v2 -namespace k8s.io", 0)
+	h.writeProc(100, 50, 100, containerCgroup(testCID), "supervisord -n", 0)
+	h.writeProc(testPID, 100, testPID, containerCgroup(testCID), "python train.py", 10*gib)
+	h.onTERM = func(pid int) {
+		h.mu.Lock()
+		delete(h.gpuPIDs, pid)
+		h.mu.Unlock()
+		_ = os.RemoveAll(filepath.Join(h.procRoot, fmt.Sprint(pid)))
+		h.writeProc(4343, 100, 4343, containerCgroup(testCID), "python train.py", 10*gib)
+	}
+
+	s.run(t, 31, nil)
+	if got := h.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d only, never the supervisor", got, testPID)
+	}
+	// The respawned process holds the memory again, so the reclaim is not
+	// reported as a success.
+	failed := s.logged("reclaim signalled but release not verified")
+	if len(failed) != 1 || failed[0]["verify"] != "partially_freed" {
+		t.Fatalf("unverified reclaims = %v, want one partially_freed:\n%s", failed, s.logs)
+	}
+	want := []string{"GPUIdleReclaimPending", "GPUReclaimFailed"}
+	if fmt.Sprint(s.events.reasons) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", s.events.reasons, want)
+	}
+}
+
+func TestMIGSliceJudgedOnlyWhenSamplerReadsIt(t *testing.T) {
+	for _, tc := range []struct {
+		sampler string
+		slice   func(g *sampling.GPUSnapshot)
+		reclaim bool
+	}{
+		// NVML and nvidia-smi report MIG slices without utilization.
+		{"nvml", func(g *sampling.GPUSnapshot) {
+			g.MIG = &sampling.MIGDevice{GPUInstanceID: 1, ComputeInstanceID: 0}
+			g.UUID, g.UtilGPU, g.UtilGPUUnknown = "MIG-5f1e", 0, true
+		}, false},
+		{"smi", func(g *sampling.GPUSnapshot) {
+			g.MIG = &sampling.MIGDevice{ParentUUID: "GPU-0", GPUInstanceID: 1, ComputeInstanceID: 0}
+			g.UUID, g.UtilGPU, g.UtilGPUUnknown = "", 0, true
+		}, false},
+		// DCGM reads SM activity per GPU instance.
+		{"dcgm", func(g *sampling.GPUSnapshot) {
+			g.MIG = &sampling.MIGDevice{ParentUUID: "GPU-0", GPUInstanceID: 1, ComputeInstanceID: -1}
+			g.UUID, g.UtilGPUUnknown = "", true
+			g.HasActivity, g.SMActivePct = true, 0
+		}, true},
+	} {
+		t.Run(tc.sampler, func(t *testing.T) {
+			s := newScenario(t, nil)
+			s.host.device = tc.slice
+
+			s.run(t, 32, nil)
+			if got := s.host.sent(); tc.reclaim != (len(got) == 1) {
+				t.Fatalf("signals = %v, want reclaim %v", got, tc.reclaim)
+			}
+			warned := s.logged("mig slice utilization unknown: pods on it are never reclaimed; use --sampler=dcgm to judge mig slices")
+			if tc.reclaim {
+				if len(warned) != 0 {
+					t.Errorf("warned about a readable slice: %v", warned)
+				}
+				return
+			}
+			// Once, not every tick.
+			if len(warned) != 1 || warned[0]["reason"] != "mig_util_unknown" || warned[0]["gpu"] != "0/gi1/ci0" {
+				t.Errorf("warnings = %v, want one mig_util_unknown for 0/gi1/ci0", warned)
+			}
+		})
+	}
+}
+
+func TestReclaimCoversEveryContainerOfThePod(t *testing.T) {
+	s := newScenario(t, nil)
+	// A second container of the same pod also holds the GPU.
+	const sidecarCID = "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"
+	s.host.writeProc(300, 50, 300, containerCgroup(sidecarCID), "python serve.py", 0)
+	s.host.writeProc(5252, 300, 300, containerCgroup(sidecarCID), "python serve.py --worker", 2*gib)
+
+	s.run(t, 31, nil)
+	got := s.host.sent()
+	want := []string{fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM), fmt.Sprintf("5252:%s", syscall.SIGTERM)}
+	sort.Strings(got)
+	sort.Strings(want)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("signals = %v, want %v", got, want)
+	}
+	if !strings.Contains(s.logs.String(), `"msg":"reclaim succeeded"`) {
+		t.Errorf("no successful reclaim in log:\n%s", s.logs)
+	}
+}
+
+func TestReclaimRunsOffTheTickLoop(t *testing.T) {
+	s := newScenario(t, nil, "--term-grace-seconds=1", "--max-reclaim-retry=0")
+	s.host.ignoreTERM[testPID] = true
+	s.run(t, 30, nil)
+
+	ctx := context.Background()
+	start := time.Now()
+	if err := s.agent.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s.clock.Advance(time.Minute)
+	if err := s.agent.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The reclaim waits out the 1s grace period; the ticks do not.
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("two ticks took %v while a reclaim was running", d)
+	}
+	if !s.agent.reclaimInFlight(idle.PodKey{UID: testPodUID}) {
+		t.Error("reclaim not in flight during the grace period")
+	}
+
+	s.agent.reclaims.Wait()
+	want := []string{fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM), fmt.Sprintf("%d:%s", testPID, syscall.SIGKILL)}
+	if got := s.host.sent(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("signals = %v, want %v", got, want)
+	}
+	if s.agent.reclaimInFlight(idle.PodKey{UID: testPodUID}) {
+		t.Error("reclaim still in flight after it finished")
+	}
+	if !strings.Contains(s.logs.String(), `"msg":"reclaim succeeded"`) {
+		t.Errorf("no successful reclaim in log:\n%s", s.logs)
+	}
+}
+
+func TestReclaimSparesPIDReusedDuringGrace(t *testing.T) {
+	s := newScenario(t, nil, "--term-grace-seconds=0")
+	// The workload exits on SIGTERM and another pod's process is started
+	// with the same PID before the SIGKILL round.
+	s.host.onTERM = func(pid int) {
+		s.host.addNeighbour(pid, 0)
+		// Not a GPU process.
+		s.host.mu.Lock()
+		delete(s.host.gpuPIDs, pid)
+		s.host.mu.Unlock()
+	}
+	s.run(t, 31, nil)
+
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM only; the reused PID must not get SIGKILL", got)
+	}
+	rec := s.logged("reclaim succeeded")
+	if len(rec) != 1 || fmt.Sprint(rec[0]["pids_reused"]) != fmt.Sprint([]int{testPID}) {
+		t.Fatalf("reclaims = %v, want one reporting pid %d as reused", rec, testPID)
+	}
+	// Signal times come from the agent's clock.
+	sigs := rec[0]["signals"].([]any)
+	if ts := sigs[0].(map[string]any)["ts"]; ts != s.clock.Now().Add(-time.Minute).Format(time.RFC3339Nano) {
+		t.Errorf("SIGTERM logged at %v, want the fake clock's time", ts)
+	}
+}
+
+func TestRecordErrorLoggerFollowsClock(t *testing.T) {
+	var logs bytes.Buffer
+	clk := clock.NewFake(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	onError := recordErrorLogger(logging.NewJSONLogger(&logs), clk, "node-a", "/var/lib/rec.jsonl")
+
+	for _, step := range []time.Duration{0, 30 * time.Second, 29 * time.Second, time.Second, 59 * time.Second, time.Minute} {
+		clk.Advance(step)
+		onError(fmt.Errorf("no space left on device"))
+	}
+	// Logged at 0s, 60s and 179s; the calls in between are throttled.
+	if n := strings.Count(logs.String(), `"msg":"record write failed"`); n != 3 {
+		t.Errorf("logged %d times, want 3:\n%s", n, logs.String())
+	}
+}
+
+func TestSamplerSwitchLogger(t *testing.T) {
+	var logs bytes.Buffer
+	m := metrics.New()
+	onSwitch := samplerSwitchLogger(logging.NewJSONLogger(&logs), m, "node-a")
+	gauge := func() string {
+		var b bytes.Buffer
+		if err := m.WriteText(&b); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(b.String(), "\n") {
+			if strings.HasPrefix(line, "gpu_reclaimer_sampler_fallback ") {
+				return line
+			}
+		}
+		return ""
+	}
+
+	onSwitch("nvidia-smi", fmt.Errorf("nvml: driver/library version mismatch"))
+	if got := gauge(); got != "gpu_reclaimer_sampler_fallback 1" {
+		t.Errorf("after fallback: %q", got)
+	}
+	onSwitch("nvml", nil)
+	if got := gauge(); got != "gpu_reclaimer_sampler_fallback 0" {
+		t.Errorf("after recovery: %q", got)
+	}
+	for _, msg := range []string{`"msg":"gpu sampler failed, falling back"`, `"msg":"gpu sampler recovered"`} {
+		if !strings.Contains(logs.String(), msg) {
+			t.Errorf("no %s in log:\n%s", msg, logs.String())
+		}
+	}
+}
+
+func TestCloseReleasesSamplerAndResolver(t *testing.T) {
+	s := newScenario(t, nil, "--pod-resources-socket="+filepath.Join(t.TempDir(), "kubelet.sock"))
+	if s.agent.resolver == nil {
+		t.Fatal("New did not keep the resolver it built")
+	}
+	if _, ok := s.agent.podRes.(*podresources.Client); !ok {
+		t.Fatalf("podRes = %T, want the *podresources.Client New built", s.agent.podRes)
+	}
+	rt := &closingRuntime{}
+	s.agent.resolver.Runtime = rt
+	pr := &closingLister{}
+	s.agent.podRes = pr
+
+	if err := s.agent.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s.host.closed != 1 || rt.closed != 1 || pr.closed != 1 {
+		t.Errorf("sampler closed %d times, runtime %d, pod resources %d; want 1 each", s.host.closed, rt.closed, pr.closed)
+	}
+}
+
+type closingLister struct{ closed int }
+
+func (l *closingLister) List(context.Context) ([]podresources.Allocation, error) { return nil, nil }
+
+func (l *closingLister) Close() error {
+	l.closed++
+	return nil
+}
+
+type closingRuntime struct{ closed int }
+
+func (r *closingRuntime) InspectContainer(context.Context, string) (attribution.ContainerMeta, error) {
+	return attribution.ContainerMeta{}, nil
+}
+
+func (r *closingRuntime) Close() error {
+	r.closed++
+	return nil
+}
@@ -1,3 +1,4 @@
 package agent
 
-// Agent implements M1 prototype logic: NVML sampling + PID attribution + dry-run candidates.
+// Agent implements NVML sampling + PID attribution + idle candidates, and
+// reclaims candidates with SIGTERM->SIGKILL unless dry-run is enabled.
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"fmt"
+	"syscall"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/config"
+)
+
+const canary = "gpu-reclaimer/canary=true"
+
+func (s *scenario) wantMode(t *testing.T, want string) {
+	t.Helper()
+	if got := modeName(s.agent.isEnforcing()); got != want {
+		t.Fatalf("mode = %s, want %s", got, want)
+	}
+}
+
+// reload applies a config loaded from the scenario's flags plus extra, as
+// the Run loop does between ticks.
+func (s *scenario) reload(t *testing.T, extra ...string) {
+	t.Helper()
+	cfg, err := config.Load(append(append([]string(nil), s.args...), extra...))
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	if err := s.agent.UpdateConfig(cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	s.agent.applySettings(<-s.agent.reloadCh)
+}
+
+// idleCycle makes the pod busy for one sample, then idle for the 31
+// samples it takes to become a candidate again.
+func (s *scenario) idleCycle(t *testing.T) {
+	t.Helper()
+	s.host.setUtil(50)
+	s.run(t, 1, nil)
+	s.host.setUtil(0)
+	s.run(t, 31, nil)
+}
+
+func TestNodeGateObserveOnlyUntilLabelMatches(t *testing.T) {
+	s := newScenario(t, nil, "--node-selector-label="+canary)
+	// Nothing known about the node yet.
+	s.wantMode(t, "observe")
+
+	s.agent.ObserveNode(map[string]string{"zone": "a"}, true)
+	s.wantMode(t, "observe")
+	s.run(t, 31, nil)
+	cands := s.logged("reclaim candidate (dry-run)")
+	if len(cands) != 1 || cands[0]["mode"] != "observe" {
+		t.Fatalf("dry-run candidates = %v, want one in observe mode", cands)
+	}
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled on a node without the label: %v", got)
+	}
+
+	// Label added, removed, added, then the Node deleted.
+	for _, step := range []struct {
+		labels  map[string]string
+		present bool
+		want    string
+	}{
+		{map[string]string{"zone": "a", "gpu-reclaimer/canary": "true"}, true, "enforce"},
+		{map[string]string{"zone": "a"}, true, "observe"},
+		{map[string]string{"gpu-reclaimer/canary": "true"}, true, "enforce"},
+		{map[string]string{"gpu-reclaimer/canary": "true"}, false, "observe"},
+	} {
+		s.agent.ObserveNode(step.labels, step.present)
+		s.wantMode(t, step.want)
+	}
+	var modes []string
+	for _, e := range s.logged("agent mode changed") {
+		modes = append(modes, fmt.Sprint(e["mode"]))
+	}
+	if fmt.Sprint(modes) != "[enforce observe enforce observe]" {
+		t.Errorf("mode changes logged = %v", modes)
+	}
+
+	// Deleted: still observe-only.
+	s.idleCycle(t)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled after the node was deleted: %v", got)
+	}
+
+	s.agent.ObserveNode(map[string]string{"gpu-reclaimer/canary": "true"}, true)
+	s.idleCycle(t)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Errorf("signals = %v, want SIGTERM to %d once the label matches", got, testPID)
+	}
+}
+
+func TestNodeGateArrivesWithConfigReload(t *testing.T) {
+	s := newScenario(t, nil)
+	s.wantMode(t, "enforce")
+
+	// The Node watch runs without a selector too, so its labels are known
+	// when a reload adds one.
+	s.agent.ObserveNode(map[string]string{"zone": "a"}, true)
+	s.wantMode(t, "enforce")
+
+	s.reload(t, "--node-selector-label="+canary)
+	s.wantMode(t, "observe")
+	s.run(t, 31, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled after the reload gated the node: %v", got)
+	}
+
+	s.agent.ObserveNode(map[string]string{"zone": "a", "gpu-reclaimer/canary": "true"}, true)
+	s.wantMode(t, "enforce")
+
+	// A reload to a selector the node does not match gates it again; one
+	// without a selector lifts the gate.
+	s.reload(t, "--node-selector-label=zone=b")
+	s.wantMode(t, "observe")
+	s.reload(t)
+	s.wantMode(t, "enforce")
+
+	// An invalid selector is rejected and the running gate kept.
+	cfg := s.agent.currentConfig()
+	cfg.NodeSelectorLabel = "=b"
+	if err := s.agent.UpdateConfig(cfg); err == nil {
+		t.Error("UpdateConfig accepted an invalid node selector")
+	}
+	s.wantMode(t, "enforce")
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/idle"
+	"gpu-reclaimer-agent/internal/podresources"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// listAllocations returns the kubelet's device allocations by device ID, or
+// nil when the cross-check is disabled or the socket is unavailable.
+func (a *Agent) listAllocations(ctx context.Context) map[string][]podresources.Allocation {
+	if a.podRes == nil {
+		return nil
+	}
+	lctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	list, err := a.podRes.List(lctx)
+	cancel()
+	if err != nil {
+		if !a.podResFailing {
+			a.log.Warn(map[string]any{"msg": "pod resources unavailable; allocation cross-check paused", "node": a.node, "error": err.Error()})
+		}
+		a.podResFailing = true
+		return nil
+	}
+	if a.podResFailing {
+		a.log.Info(map[string]any{"msg": "pod resources available again", "node": a.node})
+	}
+	a.podResFailing = false
+	return podresources.ByDevice(list)
+}
+
+// allocationMismatch describes a device the pod runs on although the device
+// plugin allocated it to other pods only. Devices the kubelet knows nothing
+// about (e.g. a pod using NVIDIA_VISIBLE_DEVICES directly) and pods without
+// a namespace/name are not judged.
+func allocationMismatch(k idle.PodKey, uuids map[string]struct{}, byDevice map[string][]podresources.Allocation) string {
+	if byDevice == nil || k.Namespace == "" || k.Name == "" {
+		return ""
+	}
+	ids := make([]string, 0, len(uuids))
+	for id := range uuids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		allocs := byDevice[id]
+		if len(allocs) == 0 {
+			continue
+		}
+		owners := map[string]struct{}{}
+		mine := false
+		for _, al := range allocs {
+			if al.Namespace == k.Namespace && al.Pod == k.Name {
+				mine = true
+				break
+			}
+			owners[al.Namespace+"/"+al.Pod] = struct{}{}
+		}
+		if !mine {
+			return fmt.Sprintf("%s allocated to %s", id, strings.Join(sortedKeys(owners), ","))
+		}
+	}
+	return ""
+}
+
+// reportMismatches logs pods whose attribution disagrees with the device
+// plugin, once per pod until it changes.
+func (a *Agent) reportMismatches(cur map[string]string) {
+	for k, detail := range cur {
+		if a.mismatched[k] != detail {
+			a.log.Warn(map[string]any{"msg": "gpu attribution disagrees with pod resources", "node": a.node, "pod": k, "detail": detail})
+		}
+	}
+	a.mismatched = cur
+	a.metrics.SetPodResourcesMismatches(len(cur))
+}
+
+// reportUnusedAllocations logs pods holding a GPU on this node without any
+// process on it, once per pod until its devices change. Such pods cannot be
+// reclaimed by killing processes, but they keep the GPU from other pods.
+func (a *Agent) reportUnusedAllocations(snap sampling.Snapshot, pods map[string]*podAgg, byDevice map[string][]podresources.Allocation) {
+	running := map[string]struct{}{}
+	for _, agg := range pods {
+		running[agg.key.Namespace+"/"+agg.key.Name] = struct{}{}
+	}
+	unused := map[string][]string{}
+	for _, g := range snap.GPUs {
+		// Only GPUs this sampler sees; other devices (NICs, ...) and
+		// unreadable GPUs are not ours to judge.
+		if g.UUID == "" || !g.Healthy() {
+			continue
+		}
+		for _, al := range byDevice[g.UUID] {
+			k := al.Namespace + "/" + al.Pod
+			if _, ok := running[k]; !ok {
+				unused[k] = append(unused[k], g.UUID)
+			}
+		}
+	}
+	cur := make(map[string]string, len(unused))
+	for k, ids := range unused {
+		sort.Strings(ids)
+		cur[k] = strings.Join(ids, ",")
+		if a.unusedAllocs[k] != cur[k] {
+			ns, name, _ := strings.Cut(k, "/")
+			a.log.Info(map[string]any{"msg": "gpu allocated without process", "node": a.node, "pod_ns": ns, "pod_name": name, "gpu_uuids": ids})
+		}
+	}
+	a.unusedAllocs = cur
+	a.metrics.SetPodResourcesUnused(len(cur))
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+type fakeKubelet struct {
+	podresourcesapi.UnimplementedPodResourcesListerServer
+	pods []*podresourcesapi.PodResources
+}
+
+func (f *fakeKubelet) List(context.Context, *podresourcesapi.ListPodResourcesRequest) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{PodResources: f.pods}, nil
+}
+
+// serveKubelet serves GPU allocations (pod "ns/name" -> device IDs) on a
+// unix socket and returns its path.
+func serveKubelet(t *testing.T, allocs map[string][]string) string {
+	t.Helper()
+	f := &fakeKubelet{}
+	for pod, ids := range allocs {
+		ns, name, _ := strings.Cut(pod, "/")
+		f.pods = append(f.pods, &podresourcesapi.PodResources{
+			Namespace: ns, Name: name,
+			Containers: []*podresourcesapi.ContainerResources{{
+				Name:    "main",
+				Devices: []*podresourcesapi.ContainerDevices{{ResourceName: "nvidia.com/gpu", DeviceIds: ids}},
+			}},
+		})
+	}
+	dir, err := os.MkdirTemp("", "podres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "kubelet.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	podresourcesapi.RegisterPodResourcesListerServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return sock
+}
+
+func TestPodResourcesMismatchSkipsReclaim(t *testing.T) {
+	// The device plugin gave GPU-0 to another pod only.
+	sock := serveKubelet(t, map[string][]string{"team-b/infer-0": {"GPU-0"}})
+	s := newScenario(t, nil, "--pod-resources-socket="+sock)
+	s.run(t, 35, nil)
+
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled despite the allocation mismatch: %v", got)
+	}
+	skips := s.logged("reclaim candidate skipped")
+	if len(skips) != 1 || skips[0]["skip_reason"] != "podresources_mismatch" || skips[0]["skip_detail"] != "GPU-0 allocated to team-b/infer-0" {
+		t.Errorf("skips = %v, want one podresources_mismatch", skips)
+	}
+	// Reported once, not every tick.
+	if n := len(s.logged("gpu attribution disagrees with pod resources")); n != 1 {
+		t.Errorf("mismatch logged %d times, want 1", n)
+	}
+	if got := unusedPods(s); fmt.Sprint(got) != "[infer-0]" {
+		t.Errorf("unused allocations logged for %v, want infer-0 once", got)
+	}
+}
+
+func TestPodResourcesTimeSlicedReplicas(t *testing.T) {
+	// Time-slicing hands out replicas "GPU-0::N"; both pods share GPU-0.
+	sock := serveKubelet(t, map[string][]string{
+		"team-a/train-0": {"GPU-0::1"},
+		"team-b/infer-0": {"GPU-0::2"},
+	})
+	s := newScenario(t, nil, "--pod-resources-socket="+sock)
+	s.run(t, 31, nil)
+
+	if got := s.host.sent(); len(got) != 1 {
+		t.Fatalf("signals = %v, want the idle pod reclaimed", got)
+	}
+	if n := len(s.logged("gpu attribution disagrees with pod resources")); n != 0 {
+		t.Errorf("replica suffix caused %d mismatches", n)
+	}
+	// infer-0 holds a replica without running anything; train-0 runs.
+	if got := unusedPods(s); fmt.Sprint(got) != "[infer-0]" {
+		t.Errorf("unused allocations logged for %v, want infer-0 once", got)
+	}
+}
+
+func unusedPods(s *scenario) []string {
+	var out []string
+	for _, e := range s.logged("gpu allocated without process") {
+		out = append(out, fmt.Sprint(e["pod_name"]))
+	}
+	return out
+}
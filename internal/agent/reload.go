@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/kube"
+	"gpu-reclaimer-agent/internal/policy"
+	"gpu-reclaimer-agent/internal/reclaim"
+)
+
+// settings is everything derived from Config that can change on reload.
+type settings struct {
+	cfg        config.Config
+	allowlist  *regexp.Regexp
+	policy     *policy.Resolver
+	protect    *policy.Protection
+	nodeSel    kube.Selector
+	nodeSelErr error
+}
+
+func compileSettings(cfg config.Config) (*settings, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	allow, err := regexp.Compile(cfg.ProcessAllowlistRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid process allowlist regex: %w", err)
+	}
+	pol, err := policy.NewResolver(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reclaim policies: %w", err)
+	}
+	protect, err := policy.NewProtection(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protection list: %w", err)
+	}
+	nodeSel, selErr := kube.ParseSelector(cfg.NodeSelectorLabel)
+	return &settings{
+		cfg:        cfg,
+		allowlist:  allow,
+		policy:     pol,
+		protect:    protect,
+		nodeSel:    nodeSel,
+		nodeSelErr: selErr,
+	}, nil
+}
+
+// UpdateConfig validates cfg and schedules it to replace the running config
+// before the next tick. On error the running config is left untouched.
+// Settings wired at startup (sampler, CRI endpoint, procfs root,
+// PodResources socket, node name, metrics address, events, state file,
+// recording) are kept and need a restart to change.
+func (a *Agent) UpdateConfig(cfg config.Config) error {
+	running := a.currentConfig()
+	var restart []string
+	if cfg.Sampler != running.Sampler || cfg.DCGMEndpoint != running.DCGMEndpoint || cfg.ReplayFile != running.ReplayFile || cfg.ReplayVirtualClock != running.ReplayVirtualClock {
+		restart = append(restart, "sampler")
+	}
+	if cfg.CRIEndpoint != running.CRIEndpoint {
+		restart = append(restart, "criEndpoint")
+	}
+	if cfg.ProcRoot != running.ProcRoot {
+		restart = append(restart, "procRoot")
+	}
+	if cfg.PodResourcesSocket != running.PodResourcesSocket {
+		restart = append(restart, "podResourcesSocket")
+	}
+	if cfg.NodeName != running.NodeName {
+		restart = append(restart, "nodeName")
+	}
+	if cfg.MetricsAddr != running.MetricsAddr {
+		restart = append(restart, "metricsAddr")
+	}
+	if cfg.EventsEnabled != running.EventsEnabled {
+		restart = append(restart, "eventsEnabled")
+	}
+	if cfg.StateFile != running.StateFile {
+		restart = append(restart, "stateFile")
+	}
+	if cfg.Record != running.Record || cfg.RecordMaxMB != running.RecordMaxMB || cfg.RecordMaxFiles != running.RecordMaxFiles {
+		restart = append(restart, "record")
+	}
+	cfg.Sampler = running.Sampler
+	cfg.DCGMEndpoint = running.DCGMEndpoint
+	cfg.ReplayFile = running.ReplayFile
+	cfg.ReplayVirtualClock = running.ReplayVirtualClock
+	cfg.CRIEndpoint = running.CRIEndpoint
+	cfg.ProcRoot = running.ProcRoot
+	cfg.PodResourcesSocket = running.PodResourcesSocket
+	cfg.NodeName = running.NodeName
+	cfg.MetricsAddr = running.MetricsAddr
+	cfg.EventsEnabled = running.EventsEnabled
+	cfg.StateFile = running.StateFile
+	cfg.Record = running.Record
+	cfg.RecordMaxMB = running.RecordMaxMB
+	cfg.RecordMaxFiles = running.RecordMaxFiles
+
+	st, err := compileSettings(cfg)
+	if err != nil {
+		return err
+	}
+	if len(restart) > 0 {
+		a.log.Warn(map[string]any{"msg": "config changes ignored until restart", "node": a.node, "fields": restart})
+	}
+
+	// Latest wins if a previous reload has not been applied yet.
+	select {
+	case <-a.reloadCh:
+	default:
+	}
+	a.reloadCh <- st
+	return nil
+}
+
+func (a *Agent) currentConfig() config.Config {
+	a.modeMu.Lock()
+	defer a.modeMu.Unlock()
+	return a.cfg
+}
+
+// applySettings runs on the Run goroutine (or in New, before Run starts).
+func (a *Agent) applySettings(st *settings) {
+	cfg := st.cfg
+	a.allowlist = st.allowlist
+	a.policy = st.policy
+	a.protect = st.protect
+	if a.nsSrc == nil && st.policy.NeedsNamespaceLabels() {
+		a.log.Warn(map[string]any{"msg": "policy tiers select on namespace labels but namespaces cannot be read; candidates those tiers could match are skipped", "node": a.node})
+	}
+	a.reclaim = reclaim.NewExecutor(time.Duration(cfg.TermGraceSeconds)*time.Second, cfg.MaxReclaimRetry)
+	if a.procRoot != "" {
+		a.reclaim.ProcRoot = a.procRoot
+	}
+	if a.signal != nil {
+		a.reclaim.Signal = a.signal
+	}
+	a.reclaim.Clock = a.clock
+	a.verify = reclaim.NewVerifier(a.sampler, time.Duration(cfg.VerifyTimeoutSeconds)*time.Second)
+	a.tracker.IdleMinutes = cfg.IdleMinutes
+	a.tracker.ConsecutiveIdleSamples = cfg.ConsecutiveIdleSamples
+	a.tracker.SampleInterval = cfg.SampleInterval
+
+	a.modeMu.Lock()
+	a.cfg = cfg
+	a.nodeGate = strings.TrimSpace(cfg.NodeSelectorLabel) != ""
+	a.nodeSel, a.nodeSelErr = st.nodeSel, st.nodeSelErr
+	a.modeMu.Unlock()
+
+	if st.nodeSelErr != nil {
+		a.log.Error(map[string]any{"msg": "invalid node selector label; agent stays observe-only", "node": a.node, "error": st.nodeSelErr.Error()})
+	}
+	a.evaluateNodeGate()
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/config"
+)
+
+func TestReloadAppliesOnNextTick(t *testing.T) {
+	s := newScenario(t, nil, "--dry-run=true")
+	s.run(t, 10, nil)
+
+	cfg, err := config.Load(append(append([]string(nil), s.args...),
+		"--idle-minutes=15", "--consecutive-idle-samples=15", "--dry-run=false"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.agent.UpdateConfig(cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	// Queued for the Run loop; the running config is untouched until then.
+	if got := s.agent.currentConfig(); !got.DryRun || got.IdleMinutes != 30 {
+		t.Fatalf("config changed before the Run loop applied it: dryRun %v idleMinutes %d", got.DryRun, got.IdleMinutes)
+	}
+	s.agent.applySettings(<-s.agent.reloadCh)
+
+	// The idle window counts from minute 0 under the new 15 minutes.
+	s.run(t, 5, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled at minute 14: %v", got)
+	}
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 || got[0] != fmt.Sprintf("%d:%s", testPID, syscall.SIGTERM) {
+		t.Fatalf("signals = %v, want SIGTERM to %d at minute 15 after the reload", got, testPID)
+	}
+	if c := s.logged("reclaim candidate (dry-run)"); len(c) != 0 {
+		t.Errorf("dry-run candidates after dryRun was reloaded off: %v", c)
+	}
+}
+
+func TestRejectedReloadKeepsRunningConfig(t *testing.T) {
+	s := newScenario(t, nil)
+	s.run(t, 10, nil)
+
+	cfg := s.agent.currentConfig()
+	cfg.IdleMinutes = 0
+	cfg.ProcessAllowlistRegex = "("
+	err := s.agent.UpdateConfig(cfg)
+	var ve *config.ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 2 {
+		t.Fatalf("UpdateConfig = %v, want both problems reported", err)
+	}
+	select {
+	case <-s.agent.reloadCh:
+		t.Fatal("a rejected config was queued")
+	default:
+	}
+
+	// Still the 30 minutes the agent started with.
+	s.run(t, 20, nil)
+	if got := s.host.sent(); len(got) != 0 {
+		t.Fatalf("signalled at minute 29: %v", got)
+	}
+	s.run(t, 1, nil)
+	if got := s.host.sent(); len(got) != 1 {
+		t.Fatalf("signals = %v, want one reclaim at minute 30", got)
+	}
+}
+
+func TestLatestReloadWins(t *testing.T) {
+	s := newScenario(t, nil)
+	for _, minutes := range []int{45, 60} {
+		cfg := s.agent.currentConfig()
+		cfg.IdleMinutes = minutes
+		if err := s.agent.UpdateConfig(cfg); err != nil {
+			t.Fatalf("UpdateConfig: %v", err)
+		}
+	}
+	s.agent.applySettings(<-s.agent.reloadCh)
+	if got := s.agent.currentConfig().IdleMinutes; got != 60 {
+		t.Errorf("idleMinutes = %d, want the latest reload's 60", got)
+	}
+	select {
+	case <-s.agent.reloadCh:
+		t.Error("an older reload is still queued")
+	default:
+	}
+}
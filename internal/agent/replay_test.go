@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/kube"
+	"gpu-reclaimer-agent/internal/logging"
+)
+
+// testdata/idle-30m.jsonl records train-0 holding 10 GiB on GPU-0 without
+// using it: one snapshot and attribution per minute from 09:00 to 09:30,
+// then the re-sample taken while validating the candidate.
+
+// replayScenario runs the agent over the recording at path with the
+// virtual clock until the recording is exhausted.
+func replayScenario(t *testing.T, path string) *scenario {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := config.Load([]string{
+		"--sampler=replay", "--replay-file=" + path, "--replay-virtual-clock=true",
+		"--dry-run=true", "--metrics-addr=",
+	})
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	s := &scenario{events: &recordedEvents{}, logs: &bytes.Buffer{}}
+	s.agent, err = New(Options{
+		Config:   cfg,
+		NodeName: "node-a",
+		Logger:   logging.NewJSONLogger(s.logs),
+		Events:   s.events,
+		Pods:     fakePods{kube.ObjectMeta{UID: testPodUID, Namespace: "team-a", Name: "train-0"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = s.agent.Close() })
+	if err := s.agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return s
+}
+
+func TestReplayReproducesIdleDecision(t *testing.T) {
+	s := replayScenario(t, filepath.Join("testdata", "idle-30m.jsonl"))
+
+	cands := s.logged("reclaim candidate (dry-run)")
+	if len(cands) != 1 {
+		t.Fatalf("%d dry-run candidates, want 1:\n%s", len(cands), s.logs)
+	}
+	c := cands[0]
+	if c["pod_name"] != "train-0" || c["idle_minutes"] != float64(30) || c["container_id"] != testCID {
+		t.Errorf("candidate = %v, want train-0 idle for 30 minutes", c)
+	}
+	if want := []string{"GPUIdleReclaimCandidate"}; strings.Join(s.events.reasons, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", s.events.reasons, want)
+	}
+	done := s.logged("replay finished")
+	if len(done) != 1 || done[0]["idle_candidates"] != float64(1) {
+		t.Errorf("replay finished = %v, want one with 1 idle candidate", done)
+	}
+}
+
+func TestReplayActivityResetsIdleWindow(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "idle-30m.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same recording with the GPU busy at 09:10.
+	busy := strings.Replace(string(b), `"time":"2026-03-01T09:10:00Z","sampler":"nvml","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":0`,
+		`"time":"2026-03-01T09:10:00Z","sampler":"nvml","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":80`, 1)
+	if busy == string(b) {
+		t.Fatal("09:10 snapshot not found in recording")
+	}
+	path := filepath.Join(t.TempDir(), "busy.jsonl")
+	if err := os.WriteFile(path, []byte(busy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := replayScenario(t, path)
+	if n := len(s.logged("reclaim candidate (dry-run)")); n != 0 {
+		t.Errorf("%d dry-run candidates after activity at 09:10, want 0:\n%s", n, s.logs)
+	}
+	if len(s.logged("replay finished")) != 1 {
+		t.Errorf("replay did not finish:\n%s", s.logs)
+	}
+}
+
+func TestReplayOfRecordedReclaimMakesSameDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	live := newScenario(t, nil, "--record="+path)
+	// A busy neighbour shares the GPU, so its PID is in every snapshot,
+	// the verify polls of the reclaims included.
+	live.host.addNeighbour(5000, 90)
+	live.host.setUtil(90)
+	live.host.procUtil[testPID] = 0
+	// train-0 is reclaimed at minute 30, its controller restarts the
+	// workload which runs for a minute, then idles until the second
+	// reclaim at minute 62.
+	live.run(t, 63, func(minute int) {
+		switch minute {
+		case 31:
+			live.host.addProc(4343, 100, "python train.py", 10*gib)
+			live.host.procUtil[4343] = 50
+		case 32:
+			live.host.procUtil[4343] = 0
+		}
+	})
+	if err := live.agent.Close(); err != nil {
+		t.Fatal(err)
+	}
+	decisions := func(s *scenario, msg string) []string {
+		var out []string
+		for _, e := range s.logged(msg) {
+			out = append(out, fmt.Sprint(e["pod_name"], " idle ", e["idle_minutes"], "m pids ", e["pids"]))
+		}
+		return out
+	}
+	want := decisions(live, "reclaim succeeded")
+	if len(want) != 2 {
+		t.Fatalf("live run reclaimed %d times, want 2:\n%s", len(want), live.logs)
+	}
+
+	replay := replayScenario(t, path)
+	if got := decisions(replay, "reclaim candidate (dry-run)"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("replayed decisions = %q, want the live ones %q", got, want)
+	}
+	// Every PID resolves as it did live: the verify polls are not replayed
+	// as ticks.
+	if n := len(replay.logged("pid attribution failed")); n != 0 {
+		t.Errorf("%d attribution failures in replay:\n%s", n, replay.logs)
+	}
+	if len(replay.logged("replay finished")) != 1 {
+		t.Errorf("replay did not finish:\n%s", replay.logs)
+	}
+}
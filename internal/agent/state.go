@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"gpu-reclaimer-agent/internal/idle"
+)
+
+// stateMaxAge bounds how long a pod that is no longer observed is tracked,
+// both in memory and across restarts.
+const stateMaxAge = 2 * time.Hour
+
+// restoreState reloads idle counters checkpointed by a previous run (PRD
+// NFR-1), so a rollout does not reset a pod that has been idle for 29 minutes.
+// States whose PIDs are gone or now belong to another container are dropped,
+// and so is everything after a downtime longer than Config.StateMaxDowntime.
+// Nobody watched the GPU meanwhile, so a shorter downtime is restored but
+// does not count as idle.
+func (a *Agent) restoreState(ctx context.Context) {
+	path := a.currentConfig().StateFile
+	if path == "" {
+		return
+	}
+	now := a.clock.Now()
+	restored, dropped, err := a.tracker.LoadCheckpoint(path, func(st *idle.PodState) bool {
+		return a.checkpointValid(ctx, st, now)
+	})
+	if err != nil {
+		a.log.Warn(map[string]any{"msg": "idle state checkpoint not restored", "node": a.node, "file": path, "error": err.Error()})
+		return
+	}
+	a.log.Info(map[string]any{"msg": "idle state restored", "node": a.node, "file": path, "restored": restored, "dropped": dropped})
+}
+
+// checkpointValid reports whether st is restored, moving its idle start
+// forward past the part of the downtime no sample covered.
+func (a *Agent) checkpointValid(ctx context.Context, st *idle.PodState, now time.Time) bool {
+	cfg := a.currentConfig()
+	gap := now.Sub(st.LastSeen)
+	if gap > stateMaxAge || gap > cfg.StateMaxDowntime {
+		return false
+	}
+	// Only idle progress is worth carrying over; an active pod restarts
+	// from zero anyway.
+	if st.IdleCount == 0 {
+		return false
+	}
+	owned := false
+	for _, pid := range st.LastEvidence.PIDs {
+		if a.ownsPID(ctx, st.Key, pid) {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return false
+	}
+	// One interval after LastSeen the next sample was due anyway.
+	if unseen := gap - cfg.SampleInterval; unseen > 0 {
+		st.IdleSince = st.IdleSince.Add(unseen)
+		st.LastEvidence.IdleSince = st.IdleSince
+	}
+	return true
+}
+
+func (a *Agent) saveState(now time.Time) {
+	path := a.currentConfig().StateFile
+	if path == "" {
+		return
+	}
+	if err := a.tracker.SaveCheckpoint(path, now); err != nil {
+		a.log.Warn(map[string]any{"msg": "idle state checkpoint failed", "node": a.node, "file": path, "error": err.Error()})
+	}
+}
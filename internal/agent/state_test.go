@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/idle"
+)
+
+type ownerAttributor struct{ attr attribution.Attribution }
+
+func (o ownerAttributor) ResolvePID(_ context.Context, pid int) (attribution.Attribution, error) {
+	a := o.attr
+	a.PID = pid
+	return a, nil
+}
+
+func TestCheckpointValidDowntime(t *testing.T) {
+	key := idle.PodKey{UID: "uid-1", ContainerID: "c1"}
+	a := &Agent{
+		cfg:    config.Config{SampleInterval: time.Minute, StateMaxDowntime: 10 * time.Minute},
+		attrib: ownerAttributor{attribution.Attribution{PodUID: "uid-1", ContainerID: "c1"}},
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	idleSince := now.Add(-30 * time.Minute)
+
+	cases := []struct {
+		name      string
+		gap       time.Duration
+		maxDown   time.Duration
+		want      bool
+		wantShift time.Duration
+	}{
+		{"next sample due", time.Minute, 0, true, 0},
+		{"rolling restart", 90 * time.Second, 0, true, 30 * time.Second},
+		{"two samples missed", 2 * time.Minute, 0, true, time.Minute},
+		{"at max downtime", 10 * time.Minute, 0, true, 9 * time.Minute},
+		{"beyond max downtime", 11 * time.Minute, 0, false, 0},
+		{"long downtime", 110 * time.Minute, 0, false, 0},
+		{"long downtime allowed", 110 * time.Minute, 3 * time.Hour, true, 109 * time.Minute},
+		{"beyond max age", 3 * time.Hour, 4 * time.Hour, false, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a.cfg.StateMaxDowntime = 10 * time.Minute
+			if c.maxDown > 0 {
+				a.cfg.StateMaxDowntime = c.maxDown
+			}
+			st := idle.PodState{
+				Key:          key,
+				IdleCount:    29,
+				IdleSince:    idleSince,
+				LastSeen:     now.Add(-c.gap),
+				LastEvidence: idle.PodEvidence{PIDs: []int{42}, IdleSince: idleSince},
+			}
+			if got := a.checkpointValid(context.Background(), &st, now); got != c.want {
+				t.Fatalf("checkpointValid after %v = %v, want %v", c.gap, got, c.want)
+			}
+			if !c.want {
+				return
+			}
+			// The downtime is not idle time.
+			if got := st.IdleSince.Sub(idleSince); got != c.wantShift || !st.LastEvidence.IdleSince.Equal(st.IdleSince) {
+				t.Errorf("idle start moved by %v (evidence %v), want %v", got, st.LastEvidence.IdleSince, c.wantShift)
+			}
+		})
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"gpu-reclaimer-agent/internal/clock"
 )
 
 type Attribution struct {
@@ -27,30 +28,64 @@ type Attribution struct {
 
 	Cmdline string
 
-	Source string // cgroup|crictl|partial
+	Source string // cgroup|cri|partial
 }
 
 type Resolver struct {
-	criEndpoint string
-	crictlPath  string
-	cache       *ttlCache
+	// ProcRoot is where procfs is read from (default "/proc"), e.g. a host
+	// /proc mounted elsewhere or a synthetic tree.
+	ProcRoot string
+	// SelfProcRoot is the agent's own procfs (default "/proc"), used to
+	// tell whether ProcRoot shows another PID namespace.
+	SelfProcRoot string
+	// Clock drives the CRI metadata cache expiry.
+	Clock clock.Clock
+	// Runtime fills in pod namespace/name and container name; nil leaves
+	// attribution to the cgroup path alone.
+	Runtime Runtime
+
+	cache *ttlCache
+	pidns pidNamespaces
 }
 
 func NewResolver(criEndpoint string) *Resolver {
-	return &Resolver{
-		criEndpoint: criEndpoint,
-		crictlPath:  findCrictl(),
-		cache:       newTTLCache(10 * time.Minute),
+	r := &Resolver{
+		ProcRoot:     "/proc",
+		SelfProcRoot: "/proc",
+		Clock:        clock.Real{},
+		Runtime:      NewCRIClient(criEndpoint),
+	}
+	r.cache = newTTLCache(10*time.Minute, func() time.Time { return r.Clock.Now() })
+	return r
+}
+
+// Close releases the runtime connection, if Runtime holds one.
+func (r *Resolver) Close() error {
+	if c, ok := r.Runtime.(io.Closer); ok {
+		return c.Close()
 	}
+	return nil
 }
 
+func (r *Resolver) procPath(pid int, name string) string {
+	return filepath.Join(r.ProcRoot, strconv.Itoa(pid), name)
+}
+
+// ResolvePID attributes a PID as reported by the sampler, i.e. in the
+// agent's PID namespace; see pidNamespaces for how it is found under
+// ProcRoot. Attribution.PID stays the agent-namespace PID, which is what
+// signals go to.
 func (r *Resolver) ResolvePID(ctx context.Context, pid int) (Attribution, error) {
 	attr := Attribution{PID: pid}
 
-	cmdline, _ := readCmdline(pid)
+	rpid, err := r.rootPID(pid)
+	if err != nil {
+		return Attribution{}, err
+	}
+	cmdline, _ := readCmdline(r.procPath(rpid, "cmdline"))
 	attr.Cmdline = cmdline
 
-	cg, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	cg, err := os.ReadFile(r.procPath(rpid, "cgroup"))
 	if err != nil {
 		return Attribution{}, err
 	}
@@ -60,22 +95,22 @@ func (r *Resolver) ResolvePID(ctx context.Context, pid int) (Attribution, error)
 	attr.ContainerID = containerID
 	attr.Source = "cgroup"
 
-	// Best-effort enrichment via crictl inspect.
-	if containerID != "" {
+	// Best-effort enrichment from the container runtime.
+	if containerID != "" && r.Runtime != nil {
 		if meta, ok := r.cache.Get(containerID); ok {
 			mergeMeta(&attr, meta)
 			if attr.PodNamespace != "" || attr.PodName != "" {
-				attr.Source = "crictl(cache)"
+				attr.Source = "cri(cache)"
 			}
 			return attr, nil
 		}
 
-		meta, err := r.inspectContainer(ctx, containerID)
+		meta, err := r.Runtime.InspectContainer(ctx, containerID)
 		if err == nil {
 			r.cache.Set(containerID, meta)
 			mergeMeta(&attr, meta)
 			if attr.PodNamespace != "" || attr.PodName != "" {
-				attr.Source = "crictl"
+				attr.Source = "cri"
 			} else {
 				attr.Source = "partial"
 			}
@@ -90,15 +125,7 @@ func (r *Resolver) ResolvePID(ctx context.Context, pid int) (Attribution, error)
 	return attr, nil
 }
 
-type crictlMeta struct {
-	PodUID        string
-	PodNamespace  string
-	PodName       string
-	ContainerName string
-	ContainerID   string
-}
-
-func mergeMeta(dst *Attribution, meta crictlMeta) {
+func mergeMeta(dst *Attribution, meta ContainerMeta) {
 	if dst.PodUID == "" {
 		dst.PodUID = meta.PodUID
 	}
@@ -116,66 +143,6 @@ func mergeMeta(dst *Attribution, meta crictlMeta) {
 	}
 }
 
-func (r *Resolver) inspectContainer(ctx context.Context, containerID string) (crictlMeta, error) {
-	if r.crictlPath == "" {
-		return crictlMeta{}, errors.New("crictl not found")
-	}
-	args := []string{"inspect", "--output", "json"}
-	if r.criEndpoint != "" {
-		args = append([]string{"-r", r.criEndpoint}, args...)
-	}
-	args = append(args, containerID)
-
-	cmd := exec.CommandContext(ctx, r.crictlPath, args...)
-	out, err := cmd.Output()
-	if err != nil {
-		return crictlMeta{}, fmt.Errorf("crictl inspect failed: %w", err)
-	}
-
-	// crictl outputs a few different JSON shapes across versions.
-	// We only need labels.
-	type labelsShape struct {
-		Info struct {
-			Config struct {
-				Labels map[string]string `json:"labels"`
-				Metadata struct {
-					Name string `json:"name"`
-				} `json:"metadata"`
-			} `json:"config"`
-		} `json:"info"`
-		Status struct {
-			Labels map[string]string `json:"labels"`
-			Metadata struct {
-				Name string `json:"name"`
-			} `json:"metadata"`
-		} `json:"status"`
-	}
-
-	var obj labelsShape
-	if err := json.Unmarshal(out, &obj); err != nil {
-		return crictlMeta{}, err
-	}
-
-	labels := map[string]string{}
-	for k, v := range obj.Info.Config.Labels {
-		labels[k] = v
-	}
-	for k, v := range obj.Status.Labels {
-		labels[k] = v
-	}
-
-	m := crictlMeta{
-		ContainerID: containerID,
-	}
-	// Common label keys
-	m.PodName = firstNonEmpty(labels["io.kubernetes.pod.name"], labels["io.kubernetes.pod.name"])
-	m.PodNamespace = firstNonEmpty(labels["io.kubernetes.pod.namespace"], labels["io.kubernetes.pod.namespace"])
-	m.ContainerName = firstNonEmpty(labels["io.kubernetes.container.name"], obj.Info.Config.Metadata.Name, obj.Status.Metadata.Name)
-	m.PodUID = firstNonEmpty(labels["io.kubernetes.pod.uid"], labels["io.kubernetes.pod.uid"])
-
-	return m, nil
-}
-
 func firstNonEmpty(vals ...string) string {
 	for _, v := range vals {
 		if strings.TrimSpace(v) != "" {
@@ -186,38 +153,90 @@ func firstNonEmpty(vals ...string) string {
 }
 
 var (
-	podUIDRe = regexp.MustCompile(`pod([0-9a-fA-F]{8}[-_][0-9a-fA-F]{4}[-_][0-9a-fA-F]{4}[-_][0-9a-fA-F]{4}[-_][0-9a-fA-F]{12})`)
-	cidRe    = regexp.MustCompile(`(?:^|/)(?:docker-|crio-|cri-containerd-|containerd-)([0-9a-fA-F]{12,64})(?:\.scope)?(?:$|/)`)
+	// Pod cgroup: "pod<uid>" (cgroupfs driver) or
+	// "kubepods[-<qos>]-pod<uid with underscores>.slice" (systemd driver),
+	// which kind's "/kubelet" cgroup root prefixes with "kubelet-".
+	// Static pods use a 32-hex config hash instead of a UUID.
+	podSegRe = regexp.MustCompile(`^(?:[a-z]+-)*pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12}|[0-9a-f]{32})(?:\.slice)?$`)
+	// Container cgroup: a runtime-prefixed systemd scope such as
+	// "cri-containerd-<id>.scope", "crio-<id>.scope" and
+	// "docker-<id>.scope", or the bare 64-hex ID of the cgroupfs driver.
+	// CRI-O's monitor "crio-conmon-<id>.scope" is deliberately not one:
+	// conmon is the container's parent, outside it, and must never be
+	// attributed to or signalled with it.
+	cidSegRe = regexp.MustCompile(`^(?:(?:docker|crio|cri-containerd|containerd|libpod)-([0-9a-f]{12,64})(?:\.scope)?|([0-9a-f]{64}))$`)
 )
 
+// parseCgroup extracts the pod UID and container ID from /proc/<pid>/cgroup,
+// for cgroup v1 (one line per hierarchy) and v2 (the "0::" line) alike:
+//
+//	cgroupfs: /kubepods/burstable/pod<uid>/<cid>
+//	systemd:  /kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/cri-containerd-<cid>.scope
+//
+// Under a private cgroup namespace paths are relative to the reader's root
+// ("/../../kubepods-..."), and with nested runtimes (kind, k3s in docker)
+// a node container's cgroup precedes the pod's; the innermost pod wins and
+// its container is the first container cgroup below it. A line with both
+// IDs is preferred over partial ones.
 func parseCgroup(cgroup string) (podUID string, containerID string) {
 	scanner := bufio.NewScanner(strings.NewReader(cgroup))
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, ":", 3)
+		parts := strings.SplitN(scanner.Text(), ":", 3)
 		if len(parts) != 3 {
 			continue
 		}
-		path := parts[2]
+		uid, cid := parseCgroupPath(parts[2])
+		if uid != "" && cid != "" {
+			return uid, cid
+		}
 		if podUID == "" {
-			if m := podUIDRe.FindStringSubmatch(path); len(m) == 2 {
-				podUID = strings.ReplaceAll(m[1], "_", "-")
-			}
+			podUID = uid
 		}
 		if containerID == "" {
-			if m := cidRe.FindStringSubmatch(path); len(m) == 2 {
-				containerID = strings.ToLower(m[1])
+			containerID = cid
+		}
+	}
+	return podUID, containerID
+}
+
+func parseCgroupPath(path string) (podUID, containerID string) {
+	segs := strings.Split(strings.ToLower(path), "/")
+	podIdx := -1
+	for i, s := range segs {
+		if m := podSegRe.FindStringSubmatch(s); m != nil {
+			podUID, podIdx = strings.ReplaceAll(m[1], "_", "-"), i
+		}
+	}
+	if podIdx >= 0 {
+		for _, s := range segs[podIdx+1:] {
+			if cid := containerSegment(s); cid != "" {
+				return podUID, cid
 			}
 		}
-		if podUID != "" && containerID != "" {
-			break
+		return podUID, ""
+	}
+	// Not under a pod (e.g. plain docker): the innermost container.
+	for i := len(segs) - 1; i >= 0; i-- {
+		if cid := containerSegment(segs[i]); cid != "" {
+			return "", cid
 		}
 	}
-	return podUID, containerID
+	return "", ""
+}
+
+func containerSegment(s string) string {
+	m := cidSegRe.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	if m[1] != "" {
+		return m[1]
+	}
+	return m[2]
 }
 
-func readCmdline(pid int) (string, error) {
-	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+func readCmdline(path string) (string, error) {
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
@@ -233,56 +252,42 @@ func readCmdline(pid int) (string, error) {
 	return strings.Join(out, " "), nil
 }
 
-func findCrictl() string {
-	// Prefer PATH lookup.
-	if p, err := exec.LookPath("crictl"); err == nil {
-		return p
-	}
-	// Common fallback locations.
-	candidates := []string{"/usr/bin/crictl", "/usr/local/bin/crictl"}
-	for _, c := range candidates {
-		if _, err := os.Stat(c); err == nil {
-			return c
-		}
-	}
-	return ""
-}
-
 // ---- tiny TTL cache ----
 
 type ttlCache struct {
 	mu  sync.Mutex
 	ttl time.Duration
+	now func() time.Time
 	m   map[string]cacheItem
 }
 
 type cacheItem struct {
-	val     crictlMeta
+	val     ContainerMeta
 	expires time.Time
 }
 
-func newTTLCache(ttl time.Duration) *ttlCache {
-	return &ttlCache{ttl: ttl, m: map[string]cacheItem{}}
+func newTTLCache(ttl time.Duration, now func() time.Time) *ttlCache {
+	return &ttlCache{ttl: ttl, now: now, m: map[string]cacheItem{}}
 }
 
-func (c *ttlCache) Get(key string) (crictlMeta, bool) {
+func (c *ttlCache) Get(key string) (ContainerMeta, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	it, ok := c.m[key]
 	if !ok {
-		return crictlMeta{}, false
+		return ContainerMeta{}, false
 	}
-	if time.Now().After(it.expires) {
+	if c.now().After(it.expires) {
 		delete(c.m, key)
-		return crictlMeta{}, false
+		return ContainerMeta{}, false
 	}
 	return it.val, true
 }
 
-func (c *ttlCache) Set(key string, val crictlMeta) {
+func (c *ttlCache) Set(key string, val ContainerMeta) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.m[key] = cacheItem{val: val, expires: time.Now().Add(c.ttl)}
+	c.m[key] = cacheItem{val: val, expires: c.now().Add(c.ttl)}
 }
 
 // For tests/debugging convenience.
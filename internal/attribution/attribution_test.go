@@ -0,0 +1,170 @@
+package attribution
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	tUID  = "2b1c7d4e-6f5a-4c3b-9a8d-1e2f3a4b5c6d"
+	tUIDu = "2b1c7d4e_6f5a_4c3b_9a8d_1e2f3a4b5c6d"
+	tCID  = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	// The node container of nested runtimes (kind, k3s in docker).
+	tNode = "d41d8cd98f00b204e9800998ecf8427ed41d8cd98f00b204e9800998ecf8427e"
+	// Static pods are named by a config hash.
+	tHash = "6d3c1b0a9f8e7d6c5b4a39281706f5e4"
+)
+
+func TestParseCgroup(t *testing.T) {
+	cases := []struct {
+		name    string
+		cgroup  string
+		wantUID string
+		wantCID string
+	}{
+		{
+			name: "cgroupfs v1 docker",
+			cgroup: "12:memory:/kubepods/burstable/pod" + tUID + "/" + tCID + "\n" +
+				"11:cpu,cpuacct:/kubepods/burstable/pod" + tUID + "/" + tCID + "\n" +
+				"1:name=systemd:/kubepods/burstable/pod" + tUID + "/" + tCID + "\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name: "cgroupfs v1 hybrid",
+			cgroup: "12:pids:/kubepods/besteffort/pod" + tUID + "/" + tCID + "\n" +
+				"1:name=systemd:/user.slice\n" +
+				"0::/\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "systemd v1 docker",
+			cgroup:  "4:memory:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice/docker-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "systemd v2 containerd",
+			cgroup:  "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + tUIDu + ".slice/cri-containerd-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "systemd v2 guaranteed",
+			cgroup:  "0::/kubepods.slice/kubepods-pod" + tUIDu + ".slice/cri-containerd-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "cgroupfs v2 containerd",
+			cgroup:  "0::/kubepods/burstable/pod" + tUID + "/" + tCID + "\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "CRI-O v2",
+			cgroup:  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice/crio-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			// CRI-O with cgroup v2 moves the workload one level down.
+			name:    "CRI-O v2 container subgroup",
+			cgroup:  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice/crio-" + tCID + ".scope/container\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			// conmon monitors the container from outside it.
+			name:    "CRI-O conmon",
+			cgroup:  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice/crio-conmon-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: "",
+		},
+		{
+			name:    "CRI-O conmon cgroupfs",
+			cgroup:  "10:memory:/kubepods/burstable/pod" + tUID + "/crio-conmon-" + tCID + "\n",
+			wantUID: tUID, wantCID: "",
+		},
+		{
+			name:    "static pod",
+			cgroup:  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tHash + ".slice/cri-containerd-" + tCID + ".scope\n",
+			wantUID: tHash, wantCID: tCID,
+		},
+		{
+			name:    "static pod cgroupfs",
+			cgroup:  "0::/kubepods/burstable/pod" + tHash + "/" + tCID + "\n",
+			wantUID: tHash, wantCID: tCID,
+		},
+		{
+			name:    "private cgroup namespace",
+			cgroup:  "0::/../../kubepods-besteffort.slice/kubepods-besteffort-pod" + tUIDu + ".slice/cri-containerd-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name: "kind",
+			cgroup: "0::/system.slice/docker-" + tNode + ".scope/kubelet.slice/kubelet-kubepods.slice/" +
+				"kubelet-kubepods-besteffort.slice/kubelet-kubepods-besteffort-pod" + tUIDu + ".slice/cri-containerd-" + tCID + ".scope\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "k3s in docker v1",
+			cgroup:  "12:memory:/docker/" + tNode + "/kubepods/besteffort/pod" + tUID + "/" + tCID + "\n",
+			wantUID: tUID, wantCID: tCID,
+		},
+		{
+			name:    "pod without container",
+			cgroup:  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice\n",
+			wantUID: tUID, wantCID: "",
+		},
+		{
+			name:    "plain docker",
+			cgroup:  "0::/system.slice/docker-" + tCID + ".scope\n",
+			wantUID: "", wantCID: tCID,
+		},
+		{
+			name:    "host process",
+			cgroup:  "0::/user.slice/user-1000.slice/session-3.scope\n",
+			wantUID: "", wantCID: "",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			uid, cid := parseCgroup(c.cgroup)
+			if uid != c.wantUID || cid != c.wantCID {
+				t.Errorf("parseCgroup = (%q, %q), want (%q, %q)", uid, cid, c.wantUID, c.wantCID)
+			}
+		})
+	}
+}
+
+// conmonRuntime fails the test if asked about anything: a conmon process
+// has no container ID to look up.
+type conmonRuntime struct{ t *testing.T }
+
+func (r conmonRuntime) InspectContainer(_ context.Context, id string) (ContainerMeta, error) {
+	r.t.Errorf("InspectContainer(%q) for a conmon process", id)
+	return ContainerMeta{ContainerID: id}, nil
+}
+
+func TestResolvePIDConmonIsNotTheContainer(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "77")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cg := "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + tUIDu + ".slice/crio-conmon-" + tCID + ".scope\n"
+	for name, body := range map[string]string{
+		"cgroup":  cg,
+		"cmdline": "/usr/bin/conmon\x00-c\x00" + tCID + "\x00",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewResolver("")
+	r.ProcRoot, r.SelfProcRoot = root, root
+	r.Runtime = conmonRuntime{t}
+
+	attr, err := r.ResolvePID(context.Background(), 77)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.PodUID != tUID || attr.ContainerID != "" || attr.Source != "partial" {
+		t.Errorf("attribution = %+v, want pod %s with no container", attr, tUID)
+	}
+}
@@ -0,0 +1,146 @@
+package attribution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Runtime looks up container metadata from the container runtime.
+type Runtime interface {
+	InspectContainer(ctx context.Context, containerID string) (ContainerMeta, error)
+}
+
+// ContainerMeta is what the runtime knows about a container's pod.
+type ContainerMeta struct {
+	PodUID        string
+	PodNamespace  string
+	PodName       string
+	ContainerName string
+	ContainerID   string
+}
+
+// DefaultCRIEndpoints are probed in order when no endpoint is configured,
+// matching crictl's defaults.
+var DefaultCRIEndpoints = []string{
+	"unix:///run/containerd/containerd.sock",
+	"unix:///run/crio/crio.sock",
+	"unix:///var/run/cri-dockerd.sock",
+}
+
+// Labels kubelet puts on every container and sandbox it creates.
+const (
+	labelPodName       = "io.kubernetes.pod.name"
+	labelPodNamespace  = "io.kubernetes.pod.namespace"
+	labelPodUID        = "io.kubernetes.pod.uid"
+	labelContainerName = "io.kubernetes.container.name"
+)
+
+// CRIClient talks to the CRI RuntimeService over its unix socket. The
+// connection is made on first use, so a runtime that starts after the agent
+// is picked up.
+type CRIClient struct {
+	endpoint string
+
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+	rt   runtimeapi.RuntimeServiceClient
+}
+
+// NewCRIClient returns a client for endpoint ("unix:///path" or a plain
+// socket path); empty probes DefaultCRIEndpoints.
+func NewCRIClient(endpoint string) *CRIClient {
+	return &CRIClient{endpoint: strings.TrimSpace(endpoint)}
+}
+
+func (c *CRIClient) client() (runtimeapi.RuntimeServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rt != nil {
+		return c.rt, nil
+	}
+	target := c.endpoint
+	if target == "" {
+		for _, ep := range DefaultCRIEndpoints {
+			if _, err := os.Stat(strings.TrimPrefix(ep, "unix://")); err == nil {
+				target = ep
+				break
+			}
+		}
+		if target == "" {
+			return nil, errors.New("no CRI socket found")
+		}
+	} else if !strings.Contains(target, "://") {
+		target = "unix://" + target
+	}
+	if !strings.HasPrefix(target, "unix://") {
+		return nil, fmt.Errorf("CRI endpoint %q: only unix sockets are supported", target)
+	}
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial CRI %s: %w", target, err)
+	}
+	c.conn, c.rt = conn, runtimeapi.NewRuntimeServiceClient(conn)
+	return c.rt, nil
+}
+
+// InspectContainer reads pod identity from the container's kubelet labels.
+// An ID the runtime does not know as a container is tried as a pod sandbox,
+// since the pause process's cgroup carries the sandbox ID.
+func (c *CRIClient) InspectContainer(ctx context.Context, containerID string) (ContainerMeta, error) {
+	rt, err := c.client()
+	if err != nil {
+		return ContainerMeta{}, err
+	}
+	resp, err := rt.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: containerID})
+	if status.Code(err) == codes.NotFound {
+		return c.inspectSandbox(ctx, rt, containerID)
+	}
+	if err != nil {
+		return ContainerMeta{}, fmt.Errorf("CRI ContainerStatus %s: %w", containerID, err)
+	}
+	st := resp.GetStatus()
+	labels := st.GetLabels()
+	return ContainerMeta{
+		ContainerID:   containerID,
+		PodUID:        labels[labelPodUID],
+		PodNamespace:  labels[labelPodNamespace],
+		PodName:       labels[labelPodName],
+		ContainerName: firstNonEmpty(labels[labelContainerName], st.GetMetadata().GetName()),
+	}, nil
+}
+
+func (c *CRIClient) inspectSandbox(ctx context.Context, rt runtimeapi.RuntimeServiceClient, id string) (ContainerMeta, error) {
+	resp, err := rt.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: id})
+	if err != nil {
+		return ContainerMeta{}, fmt.Errorf("CRI PodSandboxStatus %s: %w", id, err)
+	}
+	md := resp.GetStatus().GetMetadata()
+	labels := resp.GetStatus().GetLabels()
+	return ContainerMeta{
+		ContainerID:  id,
+		PodUID:       firstNonEmpty(md.GetUid(), labels[labelPodUID]),
+		PodNamespace: firstNonEmpty(md.GetNamespace(), labels[labelPodNamespace]),
+		PodName:      firstNonEmpty(md.GetName(), labels[labelPodName]),
+	}, nil
+}
+
+func (c *CRIClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.rt = nil, nil
+	return err
+}
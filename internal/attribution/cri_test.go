@@ -0,0 +1,163 @@
+package attribution
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// fakeRuntime answers ContainerStatus and PodSandboxStatus from maps, with
+// NotFound for unknown IDs as containerd and CRI-O do.
+type fakeRuntime struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+
+	containers map[string]*runtimeapi.ContainerStatus
+	sandboxes  map[string]*runtimeapi.PodSandboxStatus
+}
+
+func (f *fakeRuntime) ContainerStatus(_ context.Context, req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	st, ok := f.containers[req.GetContainerId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %q not found", req.GetContainerId())
+	}
+	return &runtimeapi.ContainerStatusResponse{Status: st}, nil
+}
+
+func (f *fakeRuntime) PodSandboxStatus(_ context.Context, req *runtimeapi.PodSandboxStatusRequest) (*runtimeapi.PodSandboxStatusResponse, error) {
+	st, ok := f.sandboxes[req.GetPodSandboxId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "sandbox %q not found", req.GetPodSandboxId())
+	}
+	return &runtimeapi.PodSandboxStatusResponse{Status: st}, nil
+}
+
+// serveCRI serves rt on a unix socket and returns its endpoint.
+func serveCRI(t *testing.T, rt runtimeapi.RuntimeServiceServer) string {
+	t.Helper()
+	// t.TempDir can exceed the 108-byte sun_path limit.
+	dir, err := os.MkdirTemp("", "cri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "cri.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(srv, rt)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return "unix://" + sock
+}
+
+func TestCRIClientInspectContainer(t *testing.T) {
+	const (
+		cid = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+		sid = "9a8b7c6d5e4f30211203f4e5d6c7b8a99a8b7c6d5e4f30211203f4e5d6c7b8a9"
+		uid = "2b1c7d4e-6f5a-4c3b-9a8d-1e2f3a4b5c6d"
+	)
+	rt := &fakeRuntime{
+		containers: map[string]*runtimeapi.ContainerStatus{
+			cid: {
+				Id:       cid,
+				Metadata: &runtimeapi.ContainerMetadata{Name: "trainer"},
+				Labels: map[string]string{
+					labelPodUID: uid, labelPodNamespace: "team-a", labelPodName: "train-0",
+				},
+			},
+			// Older runtimes leave the container name label off.
+			"nolabel": {Id: "nolabel", Metadata: &runtimeapi.ContainerMetadata{Name: "sidecar"}},
+		},
+		sandboxes: map[string]*runtimeapi.PodSandboxStatus{
+			sid: {
+				Id:       sid,
+				Metadata: &runtimeapi.PodSandboxMetadata{Uid: uid, Namespace: "team-a", Name: "train-0"},
+			},
+		},
+	}
+	c := NewCRIClient(serveCRI(t, rt))
+	t.Cleanup(func() { c.Close() })
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		id   string
+		want ContainerMeta
+	}{
+		{
+			name: "container",
+			id:   cid,
+			want: ContainerMeta{ContainerID: cid, PodUID: uid, PodNamespace: "team-a", PodName: "train-0", ContainerName: "trainer"},
+		},
+		{
+			name: "name from metadata",
+			id:   "nolabel",
+			want: ContainerMeta{ContainerID: "nolabel", ContainerName: "sidecar"},
+		},
+		{
+			// The pause process's cgroup names the sandbox, which
+			// ContainerStatus reports as NotFound.
+			name: "sandbox fallback",
+			id:   sid,
+			want: ContainerMeta{ContainerID: sid, PodUID: uid, PodNamespace: "team-a", PodName: "train-0"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.InspectContainer(ctx, tc.id)
+			if err != nil {
+				t.Fatalf("InspectContainer: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("InspectContainer = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := c.InspectContainer(ctx, "gone")
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("err = %v, want NotFound from PodSandboxStatus", err)
+		}
+	})
+}
+
+func TestResolverCloseReleasesCRIConnection(t *testing.T) {
+	rt := &fakeRuntime{containers: map[string]*runtimeapi.ContainerStatus{
+		"c1": {Id: "c1", Metadata: &runtimeapi.ContainerMetadata{Name: "trainer"}},
+	}}
+	r := NewResolver(serveCRI(t, rt))
+	cri := r.Runtime.(*CRIClient)
+	if _, err := cri.InspectContainer(context.Background(), "c1"); err != nil {
+		t.Fatalf("InspectContainer: %v", err)
+	}
+	conn := cri.conn
+	if conn == nil {
+		t.Fatal("no connection after a lookup")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s := conn.GetState(); s != connectivity.Shutdown || cri.conn != nil {
+		t.Errorf("after Close: state %v, conn %v", s, cri.conn)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	// A runtime without a connection has nothing to close.
+	r.Runtime = conmonRuntime{t}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close without a closable runtime: %v", err)
+	}
+}
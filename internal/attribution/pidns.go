@@ -0,0 +1,194 @@
+package attribution
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// initPIDNamespace is the inode of the host's initial PID namespace
+// (PROC_PID_INIT_INO in the kernel).
+const initPIDNamespace = 0xEFFFFFFC
+
+// ErrPIDNamespace means a sampler PID cannot be mapped to a process under
+// ProcRoot that the agent could also signal, typically because the agent
+// runs without hostPID.
+var ErrPIDNamespace = errors.New("pid namespace mismatch")
+
+// pidNamespaces compares the agent's PID namespace with the one ProcRoot
+// shows. GPU PIDs are in the agent's namespace (the driver translates them
+// for the caller) and signals are sent there, so:
+//
+//   - same namespace: PIDs are used as they are; outside the host namespace
+//     only the agent's own pod is visible and everything else fails with
+//     ErrPIDNamespace.
+//   - ProcRoot is an outer namespace (e.g. the host /proc mounted into a
+//     pod without hostPID): PIDs are translated through the NSpid lines of
+//     /proc/<pid>/status. Processes outside the agent's namespace cannot be
+//     signalled and fail with ErrPIDNamespace.
+//
+// In both cases a PID the agent's namespace has handed out (see
+// allocatedHere) that is no longer visible is a process that exited since
+// the sample, and fails with a plain not-found error instead.
+type pidNamespaces struct {
+	once   sync.Once
+	known  bool
+	selfNS uint64
+	sameNS bool
+
+	mu      sync.Mutex
+	toRoot  map[int]int
+	builtAt time.Time
+}
+
+func (r *Resolver) detectPIDNamespaces() {
+	ns := &r.pidns
+	ns.once.Do(func() {
+		self, err1 := nsInode(filepath.Join(r.SelfProcRoot, "self", "ns", "pid"))
+		// "self" resolves to the agent's PID as ProcRoot's namespace sees it.
+		rootSelf, err2 := os.Readlink(filepath.Join(r.ProcRoot, "self"))
+		if err1 != nil || err2 != nil {
+			// Cannot tell; keep the plain behaviour.
+			return
+		}
+		ns.known, ns.selfNS = true, self
+		ns.sameNS = rootSelf == strconv.Itoa(os.Getpid())
+	})
+}
+
+// PIDNamespaceMismatch reports whether ProcRoot shows a different PID
+// namespace than the agent's, i.e. PIDs under ProcRoot cannot be signalled
+// as they are.
+func (r *Resolver) PIDNamespaceMismatch() bool {
+	r.detectPIDNamespaces()
+	return r.pidns.known && !r.pidns.sameNS
+}
+
+// rootPID maps a PID in the agent's namespace to ProcRoot's namespace.
+func (r *Resolver) rootPID(pid int) (int, error) {
+	r.detectPIDNamespaces()
+	ns := &r.pidns
+	if !ns.known {
+		return pid, nil
+	}
+	if ns.sameNS {
+		if ns.selfNS != initPIDNamespace {
+			if _, err := os.Stat(r.procPath(pid, "status")); err != nil {
+				if r.allocatedHere(pid) {
+					return 0, pidGone(pid)
+				}
+				return 0, fmt.Errorf("%w: pid %d is not visible; the agent runs in its own PID namespace (needs hostPID: true)", ErrPIDNamespace, pid)
+			}
+		}
+		return pid, nil
+	}
+	if _, err := os.Stat(filepath.Join(r.SelfProcRoot, strconv.Itoa(pid))); err != nil {
+		if r.allocatedHere(pid) {
+			return 0, pidGone(pid)
+		}
+		return 0, fmt.Errorf("%w: pid %d is outside the agent's PID namespace and cannot be signalled (needs hostPID: true)", ErrPIDNamespace, pid)
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if h, ok := ns.toRoot[pid]; ok {
+		return h, nil
+	}
+	// A miss is either a new process or one that already exited; rebuild
+	// at most once a second.
+	if now := r.Clock.Now(); ns.toRoot == nil || now.Sub(ns.builtAt) >= time.Second {
+		ns.toRoot, ns.builtAt = r.buildPIDMap(ns.selfNS), now
+		if h, ok := ns.toRoot[pid]; ok {
+			return h, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: pid %d has no NSpid match under %s", ErrPIDNamespace, pid, r.ProcRoot)
+}
+
+// allocatedHere reports whether the agent's PID namespace has already handed
+// out pid, judged by its ns_last_pid: PIDs are allocated in increasing order,
+// so a larger one cannot be one of its processes. After the counter wraps at
+// pid_max, or when ns_last_pid cannot be read, the answer is false and the
+// caller keeps reporting the mismatch.
+func (r *Resolver) allocatedHere(pid int) bool {
+	b, err := os.ReadFile(filepath.Join(r.SelfProcRoot, "sys", "kernel", "ns_last_pid"))
+	if err != nil {
+		return false
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	return err == nil && pid <= last
+}
+
+func pidGone(pid int) error {
+	return fmt.Errorf("pid %d: %w (exited since the sample)", pid, os.ErrNotExist)
+}
+
+// buildPIDMap maps agent-namespace PIDs to ProcRoot PIDs for every process
+// under ProcRoot that lives in namespace selfNS; the last NSpid entry is
+// the PID in the innermost namespace.
+func (r *Resolver) buildPIDMap(selfNS uint64) map[int]int {
+	out := map[int]int{}
+	entries, err := os.ReadDir(r.ProcRoot)
+	if err != nil {
+		return out
+	}
+	for _, e := range entries {
+		h, err := strconv.Atoi(e.Name())
+		if err != nil || h <= 0 {
+			continue
+		}
+		if ino, err := nsInode(r.procPath(h, "ns/pid")); err != nil || ino != selfNS {
+			continue
+		}
+		ids, err := readNSpid(r.procPath(h, "status"))
+		if err != nil || len(ids) == 0 {
+			continue
+		}
+		out[ids[len(ids)-1]] = h
+	}
+	return out
+}
+
+// readNSpid returns the NSpid line of /proc/<pid>/status, outermost first.
+func readNSpid(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		rest, ok := strings.CutPrefix(sc.Text(), "NSpid:")
+		if !ok {
+			continue
+		}
+		var ids []int
+		for _, s := range strings.Fields(rest) {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad NSpid %q", path, rest)
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("%s: no NSpid line (kernel older than 4.1)", path)
+}
+
+// nsInode reads a namespace link such as "pid:[4026531836]".
+func nsInode(link string) (uint64, error) {
+	s, err := os.Readlink(link)
+	if err != nil {
+		return 0, err
+	}
+	i, j := strings.IndexByte(s, '['), strings.IndexByte(s, ']')
+	if i < 0 || j < i {
+		return 0, fmt.Errorf("%s: unexpected namespace link %q", link, s)
+	}
+	return strconv.ParseUint(s[i+1:j], 10, 64)
+}
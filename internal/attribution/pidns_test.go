@@ -0,0 +1,125 @@
+package attribution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestResolvePIDThroughNSpid covers the host /proc mounted as ProcRoot
+// without hostPID: the agent's PIDs are found through NSpid.
+func TestResolvePIDThroughNSpid(t *testing.T) {
+	const agentNS = "pid:[4026532001]"
+	self, host := t.TempDir(), t.TempDir()
+	mkdir := func(dir string) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path, body string) {
+		mkdir(filepath.Dir(path))
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, path string) {
+		mkdir(filepath.Dir(path))
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The agent's own procfs: its namespace and the GPU process as PID 42.
+	link(agentNS, filepath.Join(self, "self", "ns", "pid"))
+	mkdir(filepath.Join(self, "42"))
+	// The host's: the agent is some other PID there.
+	link("987654", filepath.Join(host, "self"))
+	hostProc := func(pid int, ns, nspid, cgroup string) {
+		dir := filepath.Join(host, fmt.Sprint(pid))
+		link(ns, filepath.Join(dir, "ns", "pid"))
+		write(filepath.Join(dir, "status"), "Name:\tpython\nNSpid:\t"+nspid+"\n")
+		write(filepath.Join(dir, "cgroup"), "0::"+cgroup+"\n")
+		write(filepath.Join(dir, "cmdline"), "python\x00train.py\x00")
+	}
+	podCgroup := "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + tUIDu + ".slice/cri-containerd-" + tCID + ".scope"
+	hostProc(5000, agentNS, "5000\t42", podCgroup)
+	// PID 42 of another pod's namespace must not be taken for ours.
+	hostProc(6000, "pid:[4026532999]", "6000\t42", "/kubepods.slice/other")
+
+	r := NewResolver("")
+	r.ProcRoot, r.SelfProcRoot, r.Runtime = host, self, nil
+	if !r.PIDNamespaceMismatch() {
+		t.Fatal("PIDNamespaceMismatch = false for the host /proc")
+	}
+	attr, err := r.ResolvePID(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("ResolvePID: %v", err)
+	}
+	if attr.PID != 42 || attr.PodUID != tUID || attr.ContainerID != tCID {
+		t.Errorf("attribution = %+v, want pid 42 in pod %s", attr, tUID)
+	}
+	// Not in the agent's namespace: cannot be signalled.
+	if _, err := r.ResolvePID(context.Background(), 6000); !errors.Is(err, ErrPIDNamespace) {
+		t.Errorf("ResolvePID(6000) err = %v, want ErrPIDNamespace", err)
+	}
+}
+
+// TestRootPIDMissingInOwnNamespace covers a missing PID while the agent runs
+// in its own PID namespace: one the namespace already handed out has exited,
+// anything else is reported as a mismatch.
+func TestRootPIDMissingInOwnNamespace(t *testing.T) {
+	self, root := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(self, "self", "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("pid:[4026532001]", filepath.Join(self, "self", "ns", "pid")); err != nil {
+		t.Fatal(err)
+	}
+	// ProcRoot shows the same namespace as the agent.
+	if err := os.Symlink(fmt.Sprint(os.Getpid()), filepath.Join(root, "self")); err != nil {
+		t.Fatal(err)
+	}
+	lastPID := filepath.Join(self, "sys", "kernel", "ns_last_pid")
+	if err := os.MkdirAll(filepath.Dir(lastPID), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		lastPID string // "" leaves ns_last_pid missing
+		pid     int
+		wantNS  bool
+	}{
+		{"exited", "50\n", 42, false},
+		{"never allocated here", "50\n", 5000, true},
+		{"ns_last_pid unreadable", "", 42, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_ = os.Remove(lastPID)
+			if c.lastPID != "" {
+				if err := os.WriteFile(lastPID, []byte(c.lastPID), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := NewResolver("")
+			r.ProcRoot, r.SelfProcRoot, r.Runtime = root, self, nil
+			if r.PIDNamespaceMismatch() {
+				t.Fatal("PIDNamespaceMismatch = true for the agent's own namespace")
+			}
+			_, err := r.rootPID(c.pid)
+			if err == nil {
+				t.Fatalf("rootPID(%d) succeeded for a missing process", c.pid)
+			}
+			if errors.Is(err, ErrPIDNamespace) != c.wantNS {
+				t.Errorf("rootPID(%d) err = %v, want ErrPIDNamespace %v", c.pid, err, c.wantNS)
+			}
+			if !c.wantNS && !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("rootPID(%d) err = %v, want not found", c.pid, err)
+			}
+		})
+	}
+}
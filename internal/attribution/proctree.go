@@ -0,0 +1,117 @@
+package attribution
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Process is one /proc entry's place in the process tree.
+type Process struct {
+	PID  int
+	PPID int
+	PGID int
+}
+
+// ProcessTree is a snapshot of parent/child and process group relations.
+type ProcessTree struct {
+	procs    map[int]Process
+	children map[int][]int
+}
+
+// ProcessTree reads every process under ProcRoot. Processes that exit while
+// it is read are skipped. It fails when ProcRoot's PIDs are not the ones
+// signals would reach.
+func (r *Resolver) ProcessTree() (*ProcessTree, error) {
+	if r.PIDNamespaceMismatch() {
+		return nil, fmt.Errorf("%w: %s shows another PID namespace than the agent's", ErrPIDNamespace, r.ProcRoot)
+	}
+	entries, err := os.ReadDir(r.ProcRoot)
+	if err != nil {
+		return nil, err
+	}
+	t := &ProcessTree{procs: map[int]Process{}, children: map[int][]int{}}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+		b, err := os.ReadFile(r.procPath(pid, "stat"))
+		if err != nil {
+			continue
+		}
+		p, err := parseStat(pid, string(b))
+		if err != nil {
+			continue
+		}
+		t.procs[pid] = p
+	}
+	for pid, p := range t.procs {
+		t.children[p.PPID] = append(t.children[p.PPID], pid)
+	}
+	for _, c := range t.children {
+		sort.Ints(c)
+	}
+	return t, nil
+}
+
+// parseStat reads ppid and pgrp from /proc/<pid>/stat:
+// "pid (comm) state ppid pgrp ...", where comm may contain spaces and ')'.
+func parseStat(pid int, stat string) (Process, error) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return Process{}, fmt.Errorf("stat %d: no comm", pid)
+	}
+	f := strings.Fields(stat[i+1:])
+	if len(f) < 3 {
+		return Process{}, fmt.Errorf("stat %d: short", pid)
+	}
+	ppid, err1 := strconv.Atoi(f[1])
+	pgid, err2 := strconv.Atoi(f[2])
+	if err1 != nil || err2 != nil {
+		return Process{}, fmt.Errorf("stat %d: bad ppid/pgrp", pid)
+	}
+	return Process{PID: pid, PPID: ppid, PGID: pgid}, nil
+}
+
+func (t *ProcessTree) Get(pid int) (Process, bool) {
+	p, ok := t.procs[pid]
+	return p, ok
+}
+
+// Group returns the members of process group pgid in PID order.
+func (t *ProcessTree) Group(pgid int) []int {
+	var out []int
+	for pid, p := range t.procs {
+		if p.PGID == pgid {
+			out = append(out, pid)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Subtree returns root and all its descendants, parents before children.
+func (t *ProcessTree) Subtree(root int) []int {
+	if _, ok := t.procs[root]; !ok {
+		return nil
+	}
+	out := []int{root}
+	for i := 0; i < len(out); i++ {
+		out = append(out, t.children[out[i]]...)
+	}
+	return out
+}
+
+// Ancestors returns pid's parents, nearest first, up to but excluding PID 0.
+func (t *ProcessTree) Ancestors(pid int) []int {
+	var out []int
+	seen := map[int]bool{pid: true}
+	for p, ok := t.procs[pid]; ok && p.PPID > 0 && !seen[p.PPID]; p, ok = t.procs[p.PPID] {
+		seen[p.PPID] = true
+		out = append(out, p.PPID)
+	}
+	return out
+}
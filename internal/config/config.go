@@ -1,70 +1,392 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	IdleMinutes            int
-	SampleInterval         time.Duration
-	ConsecutiveIdleSamples int
-	GPUUtilThresholdPct    int
-	TermGraceSeconds       int
-	MaxReclaimRetry        int
-	DryRun                 bool
+	IdleMinutes            int           `yaml:"idleMinutes"`
+	SampleInterval         time.Duration `yaml:"sampleInterval"`
+	ConsecutiveIdleSamples int           `yaml:"consecutiveIdleSamples"`
+	GPUUtilThresholdPct    int           `yaml:"gpuUtilThresholdPercent"`
+	TermGraceSeconds       int           `yaml:"termGraceSeconds"`
+	MaxReclaimRetry        int           `yaml:"maxReclaimRetry"`
+	VerifyTimeoutSeconds   int           `yaml:"verifyTimeoutSeconds"`
+	DryRun                 bool          `yaml:"dryRun"`
+
+	// Which processes a reclaim signals: the GPU PID itself, its process
+	// group, or its whole process tree (ReclaimTarget* constants); always
+	// limited to processes of the same container.
+	ReclaimTarget string `yaml:"reclaimTarget"`
+
+	// Namespace policy tiers, first match wins. Pods matching no tier use
+	// the global IdleMinutes/GPUUtilThresholdPct/ConsecutiveIdleSamples.
+	Policies []Policy `yaml:"policies"`
+
+	Sampler string `yaml:"sampler"`
+
+	// dcgm-exporter /metrics URL read by Sampler "dcgm". Must be the
+	// exporter on this node (e.g. via hostPort), not a cluster Service.
+	DCGMEndpoint string `yaml:"dcgmEndpoint"`
+
+	// Recording replayed by Sampler "replay". With the virtual clock the
+	// agent's notion of time follows the recorded timestamps and samples are
+	// taken back-to-back instead of every SampleInterval.
+	ReplayFile         string `yaml:"replayFile"`
+	ReplayVirtualClock bool   `yaml:"replayVirtualClock"`
+
+	// When set, every snapshot and PID attribution is appended to this JSONL
+	// file (rotated at RecordMaxMB, keeping RecordMaxFiles) as evidence for
+	// disputed reclaims; the file can be fed back through ReplayFile.
+	Record         string `yaml:"record"`
+	RecordMaxMB    int    `yaml:"recordMaxMB"`
+	RecordMaxFiles int    `yaml:"recordMaxFiles"`
+
+	// CRI RuntimeService socket used to look up pod namespace/name; empty
+	// probes the containerd, CRI-O and cri-dockerd defaults.
+	CRIEndpoint string `yaml:"criEndpoint"`
 
-	Sampler string
+	// procfs PIDs are attributed from. Mounting the host /proc here without
+	// hostPID maps GPU PIDs through NSpid; signals and the reclaim
+	// executor's zombie check still use the agent's own /proc.
+	ProcRoot string `yaml:"procRoot"`
 
-	CRIEndpoint string
+	// kubelet PodResources socket; when set, the device plugin's GPU
+	// allocations are cross-checked against PID attribution. Empty disables
+	// the check.
+	PodResourcesSocket string `yaml:"podResourcesSocket"`
 
-	// If set, only nodes with this label key/value are enabled. (M1 prototype: not enforced)
-	NodeSelectorLabel string
+	// Kubernetes node this agent runs on (downward API spec.nodeName).
+	NodeName string `yaml:"nodeName"`
+
+	// If set (label selector, e.g. "gpu-reclaimer/canary=true"), the agent only
+	// enforces reclaim while its Node matches; otherwise it is observe-only.
+	NodeSelectorLabel string `yaml:"nodeSelectorLabel"`
 
 	// Pod-level opt-out annotation key. If present and equals "false", never reclaim.
-	PodEnabledAnnotationKey string
-	PodEnabledDefault       bool
+	PodEnabledAnnotationKey string `yaml:"podEnabledAnnotationKey"`
+	PodEnabledDefault       bool   `yaml:"podEnabledDefault"`
+
+	ProcessAllowlistRegex string `yaml:"processAllowlistRegex"`
+
+	// Never reclaim pods in these namespaces (globs) or matching any of these
+	// pod label selectors (PRD FR-11).
+	ProtectedNamespaces   []string `yaml:"protectedNamespaces"`
+	ProtectedPodSelectors []string `yaml:"protectedPodSelectors"`
+
+	// Listen address for the Prometheus /metrics endpoint; empty disables it.
+	MetricsAddr string `yaml:"metricsAddr"`
 
-	ProcessAllowlistRegex string
+	// Post Kubernetes Events on candidate/reclaimed pods (requires in-cluster API access).
+	EventsEnabled bool `yaml:"eventsEnabled"`
+
+	// hostPath file where idle tracker state is checkpointed each tick and
+	// restored on startup; empty disables persistence.
+	StateFile string `yaml:"stateFile"`
+	// Longest agent downtime after which checkpointed state is still
+	// restored; the downtime itself never counts as idle.
+	StateMaxDowntime time.Duration `yaml:"stateMaxDowntime"`
+
+	// YAML/JSON config file; watched and hot-reloaded when set.
+	ConfigFile string `yaml:"-"`
+	// Keys set in ConfigFile whose value env or flags replace; editing them
+	// in the file has no effect.
+	FileOverrides []string `yaml:"-"`
 }
 
-func FromEnvAndFlags(args []string) Config {
-	fs := flag.NewFlagSet("gpu-reclaimer-agent", flag.ContinueOnError)
-	fs.SetOutput(os.Stderr)
+// Policy is a reclaim tier selected by namespace name patterns and/or a
+// namespace label selector. Zero-valued thresholds inherit the global ones.
+type Policy struct {
+	Name string `json:"name" yaml:"name"`
+	// Namespace name globs, e.g. "dev-*".
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces"`
+	// Label selector on the Namespace object, e.g. "team=research".
+	NamespaceSelector string `json:"namespaceSelector,omitempty" yaml:"namespaceSelector"`
 
-	cfg := Config{
-		IdleMinutes:            envInt("IDLE_MINUTES", 30),
-		SampleInterval:         time.Duration(envInt("SAMPLE_INTERVAL_SECONDS", 60)) * time.Second,
-		ConsecutiveIdleSamples: envInt("CONSECUTIVE_IDLE_SAMPLES", 30),
-		GPUUtilThresholdPct:    envInt("GPU_UTIL_THRESHOLD_PERCENT", 1),
-		TermGraceSeconds:       envInt("TERM_GRACE_SECONDS", 15),
-		MaxReclaimRetry:        envInt("MAX_RECLAIM_RETRY", 2),
-		DryRun:                 envBool("DRY_RUN", false),
-		Sampler:                envString("SAMPLER", "nvml"),
-		CRIEndpoint:            os.Getenv("CRI_ENDPOINT"),
-		PodEnabledAnnotationKey: envString("POD_ENABLED_ANNOTATION_KEY", "gpu-reclaimer/enabled"),
-		PodEnabledDefault:       envBool("POD_ENABLED_DEFAULT", true),
-		ProcessAllowlistRegex: envString("PROCESS_ALLOWLIST_REGEX", "(^|/)(nvidia-persistenced|nvidia-powerd)$"),
+	IdleMinutes            int `json:"idleMinutes,omitempty" yaml:"idleMinutes"`
+	GPUUtilThresholdPct    int `json:"gpuUtilThresholdPercent,omitempty" yaml:"gpuUtilThresholdPercent"`
+	ConsecutiveIdleSamples int `json:"consecutiveIdleSamples,omitempty" yaml:"consecutiveIdleSamples"`
+	// Empty inherits Config.ReclaimTarget.
+	ReclaimTarget string `json:"reclaimTarget,omitempty" yaml:"reclaimTarget"`
+}
+
+// Reclaim targets.
+const (
+	ReclaimTargetPID          = "pid"
+	ReclaimTargetProcessGroup = "process-group"
+	ReclaimTargetSubtree      = "subtree"
+)
+
+func defaults() Config {
+	return Config{
+		IdleMinutes:             30,
+		SampleInterval:          60 * time.Second,
+		ConsecutiveIdleSamples:  30,
+		GPUUtilThresholdPct:     1,
+		TermGraceSeconds:        15,
+		MaxReclaimRetry:         2,
+		ReclaimTarget:           ReclaimTargetPID,
+		VerifyTimeoutSeconds:    30,
+		DryRun:                  false,
+		Sampler:                 "nvml",
+		DCGMEndpoint:            "http://localhost:9400/metrics",
+		ReplayVirtualClock:      true,
+		RecordMaxMB:             100,
+		RecordMaxFiles:          5,
+		ProcRoot:                "/proc",
+		PodEnabledAnnotationKey: "gpu-reclaimer/enabled",
+		PodEnabledDefault:       true,
+		ProcessAllowlistRegex:   "(^|/)(nvidia-persistenced|nvidia-powerd)$",
+		ProtectedNamespaces:     []string{"kube-system"},
+		MetricsAddr:             ":9464",
+		EventsEnabled:           true,
+		StateMaxDowntime:        10 * time.Minute,
+	}
+}
+
+// Load builds the config with precedence defaults < config file < env <
+// flags, then validates it. The config file is CONFIG_FILE or --config.
+// Calling Load again with the same args re-reads the file, which is how hot
+// reload works. Invalid env values and failed validation are reported
+// together as a *ValidationError.
+func Load(args []string) (Config, error) {
+	cfg := defaults()
+
+	cfg.ConfigFile = os.Getenv("CONFIG_FILE")
+	if p := configFlag(args); p != "" {
+		cfg.ConfigFile = p
 	}
+	var fileKeys map[string]bool
+	if cfg.ConfigFile != "" {
+		b, err := os.ReadFile(cfg.ConfigFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("read config file: %w", err)
+		}
+		if err := decodeFile(b, &cfg); err != nil {
+			return Config{}, fmt.Errorf("config file %s: %w", cfg.ConfigFile, err)
+		}
+		fileKeys = topLevelKeys(b)
+	}
+	fileLayer := cfg
+
+	problems := applyEnv(&cfg)
+	if err := applyFlags(&cfg, args); err != nil {
+		return Config{}, err
+	}
+	cfg.FileOverrides = overriddenKeys(fileLayer, cfg, fileKeys)
+	if err := cfg.Validate(); err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			problems = append(problems, ve.Problems...)
+		} else {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return Config{}, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
 
+// decodeFile parses YAML (and therefore JSON) onto cfg. Unknown keys are
+// rejected so a typo does not silently fall back to a default.
+func decodeFile(b []byte, cfg *Config) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// topLevelKeys returns the keys a successfully decoded config file sets.
+func topLevelKeys(b []byte) map[string]bool {
+	var m map[string]yaml.Node
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
+
+// overriddenKeys lists, in field order, the keys in fileKeys whose value
+// differs between the file layer and the final config.
+func overriddenKeys(fileLayer, final Config, fileKeys map[string]bool) []string {
+	fv, gv := reflect.ValueOf(fileLayer), reflect.ValueOf(final)
+	var out []string
+	for i := 0; i < fv.NumField(); i++ {
+		key, _, _ := strings.Cut(fv.Type().Field(i).Tag.Get("yaml"), ",")
+		if !fileKeys[key] {
+			continue
+		}
+		if !reflect.DeepEqual(fv.Field(i).Interface(), gv.Field(i).Interface()) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// applyEnv overlays environment variables on cfg. Values that fail to parse
+// are reported rather than silently replaced by defaults.
+func applyEnv(cfg *Config) []string {
+	e := &envReader{}
+	cfg.IdleMinutes = e.Int("IDLE_MINUTES", cfg.IdleMinutes)
+	cfg.SampleInterval = e.Seconds("SAMPLE_INTERVAL_SECONDS", cfg.SampleInterval)
+	cfg.ConsecutiveIdleSamples = e.Int("CONSECUTIVE_IDLE_SAMPLES", cfg.ConsecutiveIdleSamples)
+	cfg.GPUUtilThresholdPct = e.Int("GPU_UTIL_THRESHOLD_PERCENT", cfg.GPUUtilThresholdPct)
+	cfg.TermGraceSeconds = e.Int("TERM_GRACE_SECONDS", cfg.TermGraceSeconds)
+	cfg.ReclaimTarget = e.String("RECLAIM_TARGET", cfg.ReclaimTarget)
+	cfg.MaxReclaimRetry = e.Int("MAX_RECLAIM_RETRY", cfg.MaxReclaimRetry)
+	cfg.VerifyTimeoutSeconds = e.Int("VERIFY_TIMEOUT_SECONDS", cfg.VerifyTimeoutSeconds)
+	cfg.DryRun = e.Bool("DRY_RUN", cfg.DryRun)
+	cfg.Sampler = e.String("SAMPLER", cfg.Sampler)
+	cfg.DCGMEndpoint = e.String("DCGM_ENDPOINT", cfg.DCGMEndpoint)
+	cfg.ReplayFile = e.String("REPLAY_FILE", cfg.ReplayFile)
+	cfg.ReplayVirtualClock = e.Bool("REPLAY_VIRTUAL_CLOCK", cfg.ReplayVirtualClock)
+	cfg.Record = e.String("RECORD", cfg.Record)
+	cfg.RecordMaxMB = e.Int("RECORD_MAX_MB", cfg.RecordMaxMB)
+	cfg.RecordMaxFiles = e.Int("RECORD_MAX_FILES", cfg.RecordMaxFiles)
+	cfg.CRIEndpoint = e.String("CRI_ENDPOINT", cfg.CRIEndpoint)
+	cfg.ProcRoot = e.String("PROC_ROOT", cfg.ProcRoot)
+	cfg.PodResourcesSocket = e.String("POD_RESOURCES_SOCKET", cfg.PodResourcesSocket)
+	cfg.NodeName = e.String("NODE_NAME", cfg.NodeName)
+	cfg.NodeSelectorLabel = e.String("NODE_SELECTOR_LABEL", cfg.NodeSelectorLabel)
+	cfg.PodEnabledAnnotationKey = e.String("POD_ENABLED_ANNOTATION_KEY", cfg.PodEnabledAnnotationKey)
+	cfg.PodEnabledDefault = e.Bool("POD_ENABLED_DEFAULT", cfg.PodEnabledDefault)
+	cfg.ProcessAllowlistRegex = e.String("PROCESS_ALLOWLIST_REGEX", cfg.ProcessAllowlistRegex)
+	if v := os.Getenv("PROTECTED_NAMESPACES"); v != "" {
+		cfg.ProtectedNamespaces = splitList(v, ",")
+	}
+	if v := os.Getenv("PROTECTED_POD_SELECTORS"); v != "" {
+		cfg.ProtectedPodSelectors = splitList(v, ";")
+	}
+	cfg.StateFile = e.String("STATE_FILE", cfg.StateFile)
+	cfg.StateMaxDowntime = e.Seconds("STATE_MAX_DOWNTIME_SECONDS", cfg.StateMaxDowntime)
+	cfg.MetricsAddr = e.String("METRICS_ADDR", cfg.MetricsAddr)
+	cfg.EventsEnabled = e.Bool("EVENTS_ENABLED", cfg.EventsEnabled)
+
+	if v := os.Getenv("RECLAIM_POLICIES"); v != "" {
+		var ps []Policy
+		if err := json.Unmarshal([]byte(v), &ps); err != nil {
+			e.problems = append(e.problems, fmt.Sprintf("RECLAIM_POLICIES: invalid JSON: %v", err))
+		} else {
+			cfg.Policies = ps
+		}
+	}
+	return e.problems
+}
+
+func applyFlags(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("gpu-reclaimer-agent", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "YAML/JSON config file, hot-reloaded on change (env CONFIG_FILE)")
 	fs.IntVar(&cfg.IdleMinutes, "idle-minutes", cfg.IdleMinutes, "Idle threshold in minutes")
 	fs.DurationVar(&cfg.SampleInterval, "sample-interval", cfg.SampleInterval, "Sampling interval")
 	fs.IntVar(&cfg.ConsecutiveIdleSamples, "consecutive-idle-samples", cfg.ConsecutiveIdleSamples, "Consecutive idle samples needed")
 	fs.IntVar(&cfg.GPUUtilThresholdPct, "gpu-util-threshold", cfg.GPUUtilThresholdPct, "GPU util threshold percent (util < threshold is idle)")
+	fs.IntVar(&cfg.TermGraceSeconds, "term-grace-seconds", cfg.TermGraceSeconds, "Seconds to wait after SIGTERM before SIGKILL")
+	fs.StringVar(&cfg.ReclaimTarget, "reclaim-target", cfg.ReclaimTarget, "Processes to signal: pid|process-group|subtree (within the container)")
+	fs.IntVar(&cfg.MaxReclaimRetry, "max-reclaim-retry", cfg.MaxReclaimRetry, "Extra TERM/KILL rounds for processes that survive a reclaim")
+	fs.IntVar(&cfg.VerifyTimeoutSeconds, "verify-timeout-seconds", cfg.VerifyTimeoutSeconds, "How long to wait for reclaimed PIDs and memory to leave the GPU")
+	fs.Func("policies", "Namespace policy tiers as a JSON array (overrides RECLAIM_POLICIES)", func(v string) error {
+		var ps []Policy
+		if err := json.Unmarshal([]byte(v), &ps); err != nil {
+			return err
+		}
+		cfg.Policies = ps
+		return nil
+	})
 	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Dry-run mode (no signals)")
-	fs.StringVar(&cfg.Sampler, "sampler", cfg.Sampler, "GPU sampling backend: nvml|smi")
-	fs.StringVar(&cfg.CRIEndpoint, "cri-endpoint", cfg.CRIEndpoint, "CRI runtime endpoint for crictl (optional)")
+	fs.StringVar(&cfg.Sampler, "sampler", cfg.Sampler, "GPU sampling backend: nvml|smi|dcgm|replay|auto")
+	fs.StringVar(&cfg.DCGMEndpoint, "dcgm-endpoint", cfg.DCGMEndpoint, "dcgm-exporter metrics URL on this node (--sampler=dcgm)")
+	fs.StringVar(&cfg.ReplayFile, "replay-file", cfg.ReplayFile, "JSONL recording replayed by --sampler=replay")
+	fs.BoolVar(&cfg.ReplayVirtualClock, "replay-virtual-clock", cfg.ReplayVirtualClock, "Follow recorded timestamps instead of wall time during replay")
+	fs.StringVar(&cfg.Record, "record", cfg.Record, "Append snapshots and PID attributions to this rotating JSONL file")
+	fs.IntVar(&cfg.RecordMaxMB, "record-max-mb", cfg.RecordMaxMB, "Rotate the --record file at this size")
+	fs.IntVar(&cfg.RecordMaxFiles, "record-max-files", cfg.RecordMaxFiles, "Number of --record files kept, including the live one")
+	fs.StringVar(&cfg.PodResourcesSocket, "pod-resources-socket", cfg.PodResourcesSocket, "kubelet PodResources socket for cross-checking GPU allocations, e.g. /var/lib/kubelet/pod-resources/kubelet.sock (optional)")
+	fs.StringVar(&cfg.CRIEndpoint, "cri-endpoint", cfg.CRIEndpoint, "CRI runtime socket, e.g. unix:///run/containerd/containerd.sock (optional; probed when empty)")
+	fs.StringVar(&cfg.ProcRoot, "proc-root", cfg.ProcRoot, "procfs to attribute PIDs from, e.g. the host /proc mounted at /host/proc")
+	fs.StringVar(&cfg.NodeName, "node-name", cfg.NodeName, "Kubernetes node name (defaults to hostname)")
+	fs.StringVar(&cfg.NodeSelectorLabel, "node-selector-label", cfg.NodeSelectorLabel, "Only enforce on nodes matching this label selector (k=v); observe-only elsewhere")
 	fs.StringVar(&cfg.PodEnabledAnnotationKey, "pod-enabled-annotation", cfg.PodEnabledAnnotationKey, "Pod annotation key used to enable/disable reclaim")
 	fs.BoolVar(&cfg.PodEnabledDefault, "pod-enabled-default", cfg.PodEnabledDefault, "Default pod enabled when annotation is absent")
 	fs.StringVar(&cfg.ProcessAllowlistRegex, "process-allowlist-regex", cfg.ProcessAllowlistRegex, "Regex for processes to never reclaim")
-	_ = fs.Parse(args)
+	fs.Func("protected-namespaces", "Comma-separated namespace globs never reclaimed (default kube-system)", func(v string) error {
+		cfg.ProtectedNamespaces = splitList(v, ",")
+		return nil
+	})
+	fs.Func("protected-pod-selectors", "Semicolon-separated pod label selectors never reclaimed, e.g. tier=online;app=db", func(v string) error {
+		cfg.ProtectedPodSelectors = splitList(v, ";")
+		return nil
+	})
+	fs.StringVar(&cfg.StateFile, "state-file", cfg.StateFile, "Checkpoint idle tracker state to this file and restore it on startup")
+	fs.DurationVar(&cfg.StateMaxDowntime, "state-max-downtime", cfg.StateMaxDowntime, "Discard the checkpoint after a longer agent downtime")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "Listen address for /metrics (empty disables)")
+	fs.BoolVar(&cfg.EventsEnabled, "events", cfg.EventsEnabled, "Post Kubernetes Events on candidate and reclaimed pods")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return nil
+}
 
-	return cfg
+// configFlag extracts --config/-config from args before full flag parsing,
+// since the file has to be applied underneath env and the other flags.
+func configFlag(args []string) string {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		for _, prefix := range []string{"--config", "-config"} {
+			if a == prefix && i+1 < len(args) {
+				return args[i+1]
+			}
+			if strings.HasPrefix(a, prefix+"=") {
+				return strings.TrimPrefix(a, prefix+"=")
+			}
+		}
+	}
+	return ""
 }
 
-func envString(key, def string) string {
+func splitList(v, sep string) []string {
+	var out []string
+	for _, p := range strings.Split(v, sep) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+type envReader struct {
+	problems []string
+}
+
+func (e *envReader) String(key, def string) string {
 	v := os.Getenv(key)
 	if v == "" {
 		return def
@@ -72,25 +394,36 @@ func envString(key, def string) string {
 	return v
 }
 
-func envInt(key string, def int) int {
+func (e *envReader) Int(key string, def int) int {
 	v := os.Getenv(key)
 	if v == "" {
 		return def
 	}
-	i, err := strconv.Atoi(v)
+	i, err := strconv.Atoi(strings.TrimSpace(v))
 	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: %q is not an integer", key, v))
 		return def
 	}
 	return i
 }
 
-func envBool(key string, def bool) bool {
+// Seconds reads whole seconds; def is kept as is when key is unset, so a
+// sub-second interval from the file or defaults survives.
+func (e *envReader) Seconds(key string, def time.Duration) time.Duration {
+	if os.Getenv(key) == "" {
+		return def
+	}
+	return time.Duration(e.Int(key, int(def/time.Second))) * time.Second
+}
+
+func (e *envReader) Bool(key string, def bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
 		return def
 	}
-	b, err := strconv.ParseBool(v)
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
 	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: %q is not a boolean", key, v))
 		return def
 	}
 	return b
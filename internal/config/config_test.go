@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadSampleInterval(t *testing.T) {
+	cases := []struct {
+		name string
+		file string
+		env  string
+		want time.Duration
+	}{
+		{"file sub-second", "sampleInterval: 1500ms\n", "", 1500 * time.Millisecond},
+		{"file below a second", "sampleInterval: 500ms\n", "", 500 * time.Millisecond},
+		{"env overrides file", "sampleInterval: 1500ms\n", "20", 20 * time.Second},
+		{"default", "", "", 60 * time.Second},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("SAMPLE_INTERVAL_SECONDS", c.env)
+			cfg, err := Load([]string{"--config", writeConfig(t, c.file)})
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.SampleInterval != c.want {
+				t.Errorf("SampleInterval = %v, want %v", cfg.SampleInterval, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadReportsBadEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SAMPLE_INTERVAL_SECONDS", "1m")
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "SAMPLE_INTERVAL_SECONDS") {
+		t.Fatalf("err = %v, want SAMPLE_INTERVAL_SECONDS problem", err)
+	}
+}
+
+func TestLoadProcRoot(t *testing.T) {
+	cases := []struct {
+		name string
+		file string
+		env  string
+		args []string
+		want string
+	}{
+		{"default", "", "", nil, "/proc"},
+		{"file", "procRoot: /host/proc\n", "", nil, "/host/proc"},
+		{"env overrides file", "procRoot: /host/proc\n", "/mnt/proc", nil, "/mnt/proc"},
+		{"flag overrides env", "", "/mnt/proc", []string{"--proc-root=/host/proc"}, "/host/proc"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("PROC_ROOT", c.env)
+			cfg, err := Load(append([]string{"--config", writeConfig(t, c.file)}, c.args...))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.ProcRoot != c.want {
+				t.Errorf("ProcRoot = %q, want %q", cfg.ProcRoot, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadReportsFileOverrides(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("IDLE_MINUTES", "45")
+	path := writeConfig(t, "idleMinutes: 60\ndryRun: true\ntermGraceSeconds: 20\n")
+	args := []string{"--config", path, "--dry-run=true", "--term-grace-seconds=5", "--max-reclaim-retry=4"}
+
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// dryRun is set to the file's value and maxReclaimRetry is not in the
+	// file, so neither hides a file edit.
+	if got := strings.Join(cfg.FileOverrides, ","); got != "idleMinutes,termGraceSeconds" {
+		t.Errorf("FileOverrides = %v, want [idleMinutes termGraceSeconds]", cfg.FileOverrides)
+	}
+	if cfg.IdleMinutes != 45 || cfg.TermGraceSeconds != 5 {
+		t.Errorf("idleMinutes/termGraceSeconds = %d/%d, want env and flag values 45/5", cfg.IdleMinutes, cfg.TermGraceSeconds)
+	}
+
+	// A reload after the file gains a key pinned by a flag reports it.
+	if err := os.WriteFile(path, []byte("idleMinutes: 60\nmaxReclaimRetry: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(args)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := strings.Join(cfg.FileOverrides, ","); got != "idleMinutes,maxReclaimRetry" {
+		t.Errorf("FileOverrides after reload = %v, want [idleMinutes maxReclaimRetry]", cfg.FileOverrides)
+	}
+
+	// Nothing is reported without a file.
+	cfg, err = Load([]string{"--term-grace-seconds=5"})
+	if err != nil {
+		t.Fatalf("Load without file: %v", err)
+	}
+	if len(cfg.FileOverrides) != 0 {
+		t.Errorf("FileOverrides without a file = %v", cfg.FileOverrides)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*Config)
+		// One substring per expected problem, in order.
+		want []string
+	}{
+		{"defaults", func(*Config) {}, nil},
+		{"bad allowlist regex", func(c *Config) { c.ProcessAllowlistRegex = "(nvidia-persistenced" },
+			[]string{`processAllowlistRegex "(nvidia-persistenced" does not compile`}},
+		{"interval longer than idle window", func(c *Config) {
+			c.IdleMinutes, c.SampleInterval, c.ConsecutiveIdleSamples = 5, 10*time.Minute, 1
+		}, []string{"sampleInterval 10m0s is longer than the idle window of 5m"}},
+		{"consecutive samples beyond the idle window", func(c *Config) {
+			c.IdleMinutes, c.SampleInterval, c.ConsecutiveIdleSamples = 30, time.Minute, 60
+		}, []string{"consecutiveIdleSamples 60 at 1m0s spans 59m0s, longer than idleMinutes 30; use at most 31 samples"}},
+		{"consecutive samples below one", func(c *Config) { c.ConsecutiveIdleSamples = 0 },
+			[]string{"consecutiveIdleSamples must be >= 1 (got 0)"}},
+		{"policy consecutive samples beyond its window", func(c *Config) {
+			c.Policies = []Policy{{Name: "dev", Namespaces: []string{"dev-*"}, IdleMinutes: 10, ConsecutiveIdleSamples: 20}}
+		}, []string{`policy "dev": consecutiveIdleSamples 20 at 1m0s spans 19m0s`}},
+		{"unknown sampler", func(c *Config) { c.Sampler = "rocm" },
+			[]string{`sampler "rocm" is unknown (want one of nvml, smi`}},
+		{"sampler is case-insensitive", func(c *Config) { c.Sampler = " SMI " }, nil},
+		{"set-based protected selector", func(c *Config) { c.ProtectedPodSelectors = []string{"tier in (online,critical)"} },
+			[]string{`protectedPodSelectors: selector "tier in (online,critical)"`}},
+		{"several problems together", func(c *Config) {
+			c.IdleMinutes = 0
+			c.GPUUtilThresholdPct = 101
+			c.Sampler = "rocm"
+			c.ProcessAllowlistRegex = "["
+			c.Policies = []Policy{{Name: "dev"}, {Name: "dev", Namespaces: []string{"a"}}}
+		}, []string{
+			"idleMinutes must be > 0 (got 0)",
+			"gpuUtilThresholdPercent must be within 1..100 (got 101)",
+			`sampler "rocm" is unknown`,
+			`processAllowlistRegex "[" does not compile`,
+			`policy "dev": needs namespaces and/or namespaceSelector`,
+			`policy "dev" is defined more than once`,
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := defaults()
+			c.mutate(&cfg)
+			err := cfg.Validate()
+			if len(c.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if len(ve.Problems) != len(c.want) {
+				t.Fatalf("problems = %q, want %d", ve.Problems, len(c.want))
+			}
+			for i, w := range c.want {
+				if !strings.Contains(ve.Problems[i], w) {
+					t.Errorf("problem %d = %q, want it to mention %q", i, ve.Problems[i], w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/kube"
+)
+
+// ValidationError lists every problem found, so one failed start shows all
+// of them instead of one per restart.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// KnownSamplers are the accepted values of Config.Sampler.
+var KnownSamplers = []string{"nvml", "smi", "nvidia-smi", "nvidiasmi", "dcgm", "replay", "auto"}
+
+// ReclaimTargets are the accepted values of Config.ReclaimTarget.
+var ReclaimTargets = []string{ReclaimTargetPID, ReclaimTargetProcessGroup, ReclaimTargetSubtree}
+
+// Validate checks every field and returns a *ValidationError listing all
+// problems, or nil.
+func (c Config) Validate() error {
+	var p problems
+
+	p.check(c.IdleMinutes > 0, "idleMinutes must be > 0 (got %d)", c.IdleMinutes)
+	p.check(c.SampleInterval > 0, "sampleInterval must be > 0 (got %s)", c.SampleInterval)
+	if c.IdleMinutes > 0 && c.SampleInterval > 0 {
+		p.check(c.SampleInterval <= time.Duration(c.IdleMinutes)*time.Minute,
+			"sampleInterval %s is longer than the idle window of %dm", c.SampleInterval, c.IdleMinutes)
+	}
+	p.windowConsistent("", c.IdleMinutes, c.ConsecutiveIdleSamples, c.SampleInterval)
+	p.check(c.GPUUtilThresholdPct >= 1 && c.GPUUtilThresholdPct <= 100,
+		"gpuUtilThresholdPercent must be within 1..100 (got %d); util < threshold counts as idle", c.GPUUtilThresholdPct)
+	p.check(c.TermGraceSeconds >= 0, "termGraceSeconds must be >= 0 (got %d)", c.TermGraceSeconds)
+	p.check(c.MaxReclaimRetry >= 0, "maxReclaimRetry must be >= 0 (got %d)", c.MaxReclaimRetry)
+	p.check(contains(ReclaimTargets, c.ReclaimTarget), "reclaimTarget %q is unknown (want one of %s)", c.ReclaimTarget, strings.Join(ReclaimTargets, ", "))
+	p.check(c.VerifyTimeoutSeconds > 0, "verifyTimeoutSeconds must be > 0 (got %d)", c.VerifyTimeoutSeconds)
+
+	sampler := strings.ToLower(strings.TrimSpace(c.Sampler))
+	p.check(contains(KnownSamplers, sampler), "sampler %q is unknown (want one of %s)", c.Sampler, strings.Join(KnownSamplers, ", "))
+	if sampler == "dcgm" {
+		if u, err := url.Parse(c.DCGMEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("dcgmEndpoint %q must be an http(s) URL", c.DCGMEndpoint)
+		}
+	}
+	if sampler == "replay" {
+		p.check(strings.TrimSpace(c.ReplayFile) != "", "sampler replay needs replayFile")
+		// Recorded PIDs belong to another host or have long been recycled.
+		p.check(c.DryRun, "sampler replay requires dryRun")
+		p.check(c.Record == "", "record cannot be combined with sampler replay")
+	}
+	if c.Record != "" {
+		if fi, err := os.Stat(filepath.Dir(c.Record)); err != nil || !fi.IsDir() {
+			p.add("record %q: directory %s does not exist", c.Record, filepath.Dir(c.Record))
+		}
+		p.check(c.RecordMaxMB > 0, "recordMaxMB must be > 0 (got %d)", c.RecordMaxMB)
+		p.check(c.RecordMaxFiles >= 1, "recordMaxFiles must be >= 1 (got %d)", c.RecordMaxFiles)
+	}
+
+	if _, err := regexp.Compile(c.ProcessAllowlistRegex); err != nil {
+		p.add("processAllowlistRegex %q does not compile: %v", c.ProcessAllowlistRegex, err)
+	}
+	p.check(strings.TrimSpace(c.PodEnabledAnnotationKey) != "", "podEnabledAnnotationKey must not be empty")
+	if _, err := kube.ParseSelector(c.NodeSelectorLabel); err != nil {
+		p.add("nodeSelectorLabel: %v", err)
+	}
+	for _, pat := range c.ProtectedNamespaces {
+		if _, err := path.Match(pat, ""); err != nil {
+			p.add("protectedNamespaces: bad pattern %q: %v", pat, err)
+		}
+	}
+	for _, sel := range c.ProtectedPodSelectors {
+		if _, err := kube.ParseSelector(sel); err != nil {
+			p.add("protectedPodSelectors: %v", err)
+		}
+	}
+	if s := c.PodResourcesSocket; s != "" && !filepath.IsAbs(strings.TrimPrefix(s, "unix://")) {
+		p.add("podResourcesSocket %q must be an absolute unix socket path", s)
+	}
+	p.check(c.StateMaxDowntime > 0, "stateMaxDowntime must be > 0 (got %s)", c.StateMaxDowntime)
+	if c.StateFile != "" {
+		if fi, err := os.Stat(filepath.Dir(c.StateFile)); err != nil || !fi.IsDir() {
+			p.add("stateFile %q: directory %s does not exist", c.StateFile, filepath.Dir(c.StateFile))
+		}
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			p.add("metricsAddr %q: %v", c.MetricsAddr, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, pol := range c.Policies {
+		name := pol.Name
+		if strings.TrimSpace(name) == "" {
+			name = fmt.Sprintf("policies[%d]", i)
+			p.add("%s: name is required", name)
+		} else if seen[name] {
+			p.add("policy %q is defined more than once", name)
+		}
+		seen[name] = true
+		p.check(len(pol.Namespaces) > 0 || strings.TrimSpace(pol.NamespaceSelector) != "",
+			"policy %q: needs namespaces and/or namespaceSelector", name)
+		for _, pat := range pol.Namespaces {
+			if _, err := path.Match(pat, ""); err != nil {
+				p.add("policy %q: bad namespace pattern %q: %v", name, pat, err)
+			}
+		}
+		if _, err := kube.ParseSelector(pol.NamespaceSelector); err != nil {
+			p.add("policy %q: %v", name, err)
+		}
+		p.check(pol.IdleMinutes >= 0, "policy %q: idleMinutes must be >= 0 (got %d)", name, pol.IdleMinutes)
+		p.check(pol.GPUUtilThresholdPct >= 0 && pol.GPUUtilThresholdPct <= 100,
+			"policy %q: gpuUtilThresholdPercent must be within 0..100 (got %d)", name, pol.GPUUtilThresholdPct)
+		p.check(pol.ConsecutiveIdleSamples >= 0, "policy %q: consecutiveIdleSamples must be >= 0 (got %d)", name, pol.ConsecutiveIdleSamples)
+		if pol.IdleMinutes > 0 && pol.ConsecutiveIdleSamples > 0 {
+			p.windowConsistent(fmt.Sprintf("policy %q: ", name), pol.IdleMinutes, pol.ConsecutiveIdleSamples, c.SampleInterval)
+		}
+		p.check(pol.ReclaimTarget == "" || contains(ReclaimTargets, pol.ReclaimTarget),
+			"policy %q: reclaimTarget %q is unknown (want one of %s)", name, pol.ReclaimTarget, strings.Join(ReclaimTargets, ", "))
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+type problems []string
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *problems) check(ok bool, format string, args ...any) {
+	if !ok {
+		p.add(format, args...)
+	}
+}
+
+// windowConsistent flags a consecutive-sample count that cannot be reached
+// within the idle window: N samples span (N-1) intervals, and if that is
+// longer than idleMinutes the effective idle window silently grows.
+func (p *problems) windowConsistent(prefix string, idleMinutes, consecutive int, interval time.Duration) {
+	if consecutive < 1 {
+		p.add("%sconsecutiveIdleSamples must be >= 1 (got %d)", prefix, consecutive)
+		return
+	}
+	if idleMinutes <= 0 || interval <= 0 {
+		return
+	}
+	window := time.Duration(idleMinutes) * time.Minute
+	span := time.Duration(consecutive-1) * interval
+	if span > window {
+		p.add("%sconsecutiveIdleSamples %d at %s spans %s, longer than idleMinutes %d; use at most %d samples or raise idleMinutes",
+			prefix, consecutive, interval, span, idleMinutes, int(window/interval)+1)
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
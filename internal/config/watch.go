@@ -0,0 +1,34 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+)
+
+// WatchFile polls path every interval and calls changed whenever its content
+// differs from the previous read. ConfigMap volumes are updated through an
+// atomic symlink swap, so comparing content is more reliable than mtimes or
+// inotify on the file itself.
+func WatchFile(ctx context.Context, path string, interval time.Duration, changed func()) {
+	last, _ := os.ReadFile(path)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		cur, err := os.ReadFile(path)
+		if err != nil {
+			// Transient during a ConfigMap swap; try again next round.
+			continue
+		}
+		if !bytes.Equal(cur, last) {
+			last = cur
+			changed()
+		}
+	}
+}
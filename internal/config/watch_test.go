@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// Swapped in through a rename like a ConfigMap update, so a poll never
+	// reads a half-written file.
+	write := func(body string) {
+		t.Helper()
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("idleMinutes: 30\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		WatchFile(ctx, path, 5*time.Millisecond, func() { changed <- struct{}{} })
+		close(done)
+	}()
+	wantChange := func() {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("change not detected")
+		}
+	}
+	wantNoChange := func() {
+		t.Helper()
+		// Long enough for several polls.
+		time.Sleep(50 * time.Millisecond)
+		if n := len(changed); n != 0 {
+			t.Fatalf("changed called %d times, want none", n)
+		}
+	}
+
+	wantNoChange()
+	write("idleMinutes: 60\n")
+	wantChange()
+
+	// Rewriting the same content is not a change.
+	write("idleMinutes: 60\n")
+	wantNoChange()
+
+	// A file missing mid ConfigMap swap is skipped, not reported.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	wantNoChange()
+	write("idleMinutes: 60\n")
+	wantNoChange()
+
+	write("idleMinutes: 90\n")
+	wantChange()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchFile did not return after cancel")
+	}
+}
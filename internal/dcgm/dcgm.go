@@ -0,0 +1,259 @@
+// Package dcgm samples GPUs from a dcgm-exporter /metrics endpoint. Besides
+// utilization and framebuffer usage it reads the profiling fields SM active
+// and tensor pipe active, which are much better idle signals than
+// utilization.gpu (a single tiny kernel per sample period keeps that at
+// 100%). dcgm-exporter does not export PIDs, so compute processes come from
+// a ProcessLister, by default nvidia-smi.
+package dcgm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/sampling"
+	"gpu-reclaimer-agent/internal/smi"
+)
+
+// DefaultEndpoint is dcgm-exporter's default listen address.
+const DefaultEndpoint = "http://localhost:9400/metrics"
+
+// dcgm-exporter field names (default counters CSV of GPU Operator).
+const (
+	fieldGPUUtil      = "DCGM_FI_DEV_GPU_UTIL"
+	fieldMemCopyUtil  = "DCGM_FI_DEV_MEM_COPY_UTIL"
+	fieldFBUsed       = "DCGM_FI_DEV_FB_USED"
+	fieldFBFree       = "DCGM_FI_DEV_FB_FREE"
+	fieldFBReserved   = "DCGM_FI_DEV_FB_RESERVED"
+	fieldSMActive     = "DCGM_FI_PROF_SM_ACTIVE"
+	fieldTensorActive = "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE"
+)
+
+// ProcessLister returns the compute processes per GPU UUID.
+type ProcessLister interface {
+	ComputeProcs(ctx context.Context) (map[string][]sampling.GPUProcess, error)
+}
+
+// MIGPlacer is optionally implemented by a ProcessLister that can tell which
+// MIG GPU instance a process runs on.
+type MIGPlacer interface {
+	MIGPlacements(ctx context.Context) (map[int]smi.MIGPlacement, error)
+}
+
+type Sampler struct {
+	Endpoint string
+	HTTP     *http.Client
+	Procs    ProcessLister
+}
+
+func New(endpoint string) *Sampler {
+	if strings.TrimSpace(endpoint) == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Sampler{
+		Endpoint: endpoint,
+		HTTP:     &http.Client{Timeout: 5 * time.Second},
+		Procs:    smi.New("nvidia-smi"),
+	}
+}
+
+func (s *Sampler) Name() string { return "dcgm" }
+
+func (s *Sampler) Close() error { return nil }
+
+func (s *Sampler) Sample(ctx context.Context) (sampling.Snapshot, error) {
+	samples, err := s.scrape(ctx)
+	if err != nil {
+		return sampling.Snapshot{}, err
+	}
+	gpus, err := buildGPUs(samples)
+	if err != nil {
+		return sampling.Snapshot{}, err
+	}
+
+	procs, err := s.Procs.ComputeProcs(ctx)
+	if err != nil {
+		return sampling.Snapshot{}, fmt.Errorf("list compute processes: %w", err)
+	}
+	byUUID := map[string]int{}
+	byInstance := map[string]int{}
+	migParent := map[string]int{}
+	for i, g := range gpus {
+		if g.MIG == nil {
+			byUUID[g.UUID] = i
+		} else {
+			migParent[g.MIG.ParentUUID] = g.Index
+			byInstance[fmt.Sprintf("%s/%d", g.MIG.ParentUUID, g.MIG.GPUInstanceID)] = i
+		}
+	}
+	var place map[int]smi.MIGPlacement
+	if mp, ok := s.Procs.(MIGPlacer); ok && len(migParent) > 0 {
+		// Best-effort: unplaced processes fall back to the entry below.
+		place, _ = mp.MIGPlacements(ctx)
+	}
+	for uuid, list := range procs {
+		for _, p := range list {
+			if pl, ok := place[p.PID]; ok {
+				if i, ok := byInstance[fmt.Sprintf("%s/%d", uuid, pl.GPUInstanceID)]; ok {
+					gpus[i].ComputeProcs = append(gpus[i].ComputeProcs, p)
+					continue
+				}
+			}
+			i, ok := byUUID[uuid]
+			if !ok {
+				idx, isMIG := migParent[uuid]
+				if !isMIG {
+					// The endpoint is probably another node's exporter; pairing
+					// its utilization with our PIDs would be wrong.
+					return sampling.Snapshot{}, fmt.Errorf("dcgm endpoint %s does not report local GPU %s", s.Endpoint, uuid)
+				}
+				// A MIG process whose instance is unknown is kept on a
+				// whole-GPU entry with unknown load: visible and attributed,
+				// but never judged idle.
+				gpus = append(gpus, sampling.GPUSnapshot{
+					Index: idx, UUID: uuid,
+					UtilGPUUnknown: true, UtilMemUnknown: true, MemUsedUnknown: true, MemTotalUnknown: true,
+				})
+				i = len(gpus) - 1
+				byUUID[uuid] = i
+			}
+			gpus[i].ComputeProcs = append(gpus[i].ComputeProcs, p)
+		}
+	}
+	return sampling.Snapshot{GPUs: gpus}, nil
+}
+
+func (s *Sampler) scrape(ctx context.Context) ([]sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("dcgm scrape: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("dcgm scrape: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	samples, err := parseText(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("dcgm scrape: %w", err)
+	}
+	return samples, nil
+}
+
+// buildGPUs folds the per-field series into one snapshot per GPU, keyed by
+// the exporter's "UUID" label, and per MIG GPU instance when the series
+// carries a "GPU_I_ID" label.
+func buildGPUs(samples []sample) ([]sampling.GPUSnapshot, error) {
+	type acc struct {
+		g                     sampling.GPUSnapshot
+		fbUsed, fbFree, fbRes float64
+		sm, util, memUtil, fb bool
+	}
+	byKey := map[string]*acc{}
+	for _, smp := range samples {
+		if !strings.HasPrefix(smp.Name, "DCGM_FI_") {
+			continue
+		}
+		uuid := smp.Labels["UUID"]
+		if uuid == "" {
+			continue
+		}
+		key := uuid
+		gi := smp.Labels["GPU_I_ID"]
+		if gi != "" {
+			key += "/" + gi
+		}
+		a := byKey[key]
+		if a == nil {
+			idx, err := strconv.Atoi(smp.Labels["gpu"])
+			if err != nil {
+				return nil, fmt.Errorf("dcgm: GPU %s has no numeric gpu label", uuid)
+			}
+			a = &acc{g: sampling.GPUSnapshot{Index: idx, UUID: uuid}}
+			if gi != "" {
+				giID, err := strconv.Atoi(gi)
+				if err != nil {
+					return nil, fmt.Errorf("dcgm: GPU %s has bad GPU_I_ID %q", uuid, gi)
+				}
+				// The exporter labels instances with the parent's UUID only.
+				a.g.UUID = ""
+				a.g.MIG = &sampling.MIGDevice{ParentUUID: uuid, GPUInstanceID: giID, ComputeInstanceID: -1}
+			}
+			byKey[key] = a
+		}
+		switch smp.Name {
+		case fieldGPUUtil:
+			a.g.UtilGPU = pct(smp.Value)
+			a.util = true
+		case fieldMemCopyUtil:
+			a.g.UtilMem = pct(smp.Value)
+			a.memUtil = true
+		case fieldFBUsed:
+			a.fbUsed = smp.Value
+			a.fb = true
+		case fieldFBFree:
+			a.fbFree = smp.Value
+		case fieldFBReserved:
+			a.fbRes = smp.Value
+		case fieldSMActive:
+			a.g.SMActivePct = ratioPct(smp.Value)
+			a.sm = true
+		case fieldTensorActive:
+			a.g.TensorActivePct = ratioPct(smp.Value)
+		}
+	}
+
+	gpus := make([]sampling.GPUSnapshot, 0, len(byKey))
+	for _, a := range byKey {
+		const mib = 1024 * 1024
+		a.g.MemUsedBytes = uint64(a.fbUsed) * mib
+		a.g.MemTotalBytes = uint64(a.fbUsed+a.fbFree+a.fbRes) * mib
+		// Profiling metrics need a datacenter GPU and the DCP module; without
+		// SM active the card-level utilization remains the signal.
+		a.g.HasActivity = a.sm
+		// Fields missing from the exporter's counter set are unknown, not 0.
+		a.g.UtilGPUUnknown = !a.util
+		a.g.UtilMemUnknown = !a.memUtil
+		a.g.MemUsedUnknown = !a.fb
+		a.g.MemTotalUnknown = !a.fb
+		gpus = append(gpus, a.g)
+	}
+	sort.Slice(gpus, func(i, j int) bool {
+		if gpus[i].Index != gpus[j].Index {
+			return gpus[i].Index < gpus[j].Index
+		}
+		return gpus[i].ID() < gpus[j].ID()
+	})
+	return gpus, nil
+}
+
+func pct(v float64) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return uint32(v + 0.5)
+}
+
+// ratioPct converts a DCGM profiling ratio (0..1) to percent.
+func ratioPct(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 100
+	}
+	return v * 100
+}
@@ -0,0 +1,186 @@
+package dcgm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/sampling"
+	"gpu-reclaimer-agent/internal/smi"
+)
+
+const (
+	a100 = "GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10"
+	t4   = "GPU-8f2a1d44-0b3c-4e5d-a6f7-1b2c3d4e5f60"
+	mib  = 1 << 20
+)
+
+// fakeProcs stands in for nvidia-smi.
+type fakeProcs struct {
+	procs map[string][]sampling.GPUProcess
+	place map[int]smi.MIGPlacement
+}
+
+func (f fakeProcs) ComputeProcs(context.Context) (map[string][]sampling.GPUProcess, error) {
+	return f.procs, nil
+}
+
+func (f fakeProcs) MIGPlacements(context.Context) (map[int]smi.MIGPlacement, error) {
+	return f.place, nil
+}
+
+// serveExporter serves testdata/name as dcgm-exporter's /metrics.
+func serveExporter(t *testing.T, name string) string {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/metrics"
+}
+
+func newSampler(endpoint string, procs fakeProcs) *Sampler {
+	s := New(endpoint)
+	s.Procs = procs
+	return s
+}
+
+func TestSample(t *testing.T) {
+	s := newSampler(serveExporter(t, "dcgm-exporter.txt"), fakeProcs{procs: map[string][]sampling.GPUProcess{
+		a100: {{PID: 23456, UsedBytes: 15000 * mib}},
+		t4:   {{PID: 23500, UsedBytes: 1900 * mib}},
+	}})
+	snap, err := s.Sample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.GPUs) != 2 {
+		t.Fatalf("got %d GPUs, want 2: %+v", len(snap.GPUs), snap.GPUs)
+	}
+	a, b := snap.GPUs[0], snap.GPUs[1]
+
+	if a.UUID != a100 || a.UtilGPU != 100 || a.UtilMem != 3 || a.MemUsedBytes != 15360*mib || a.MemTotalBytes != (15360+65536+1024)*mib {
+		t.Errorf("GPU 0 = %+v", a)
+	}
+	// The ratios become percent.
+	if !a.HasActivity || a.SMActivePct < 0.45 || a.SMActivePct > 0.46 || a.TensorActivePct != 0 {
+		t.Errorf("GPU 0 activity = %v sm %v tensor %v", a.HasActivity, a.SMActivePct, a.TensorActivePct)
+	}
+	// One tiny kernel keeps utilization.gpu at 100; SM activity shows idle.
+	if busy, ok := a.BusyPct(); !ok || busy > 1 {
+		t.Errorf("GPU 0 BusyPct = %v, %v; want SM activity", busy, ok)
+	}
+	if len(a.ComputeProcs) != 1 || a.ComputeProcs[0].PID != 23456 {
+		t.Errorf("GPU 0 procs = %+v", a.ComputeProcs)
+	}
+
+	// No profiling fields (T4 without DCP): utilization.gpu is the signal.
+	if b.UUID != t4 || b.HasActivity {
+		t.Errorf("GPU 1 = %+v", b)
+	}
+	if busy, ok := b.BusyPct(); !ok || busy != 37 {
+		t.Errorf("GPU 1 BusyPct = %v, %v; want utilization 37", busy, ok)
+	}
+}
+
+func TestSampleMIG(t *testing.T) {
+	s := newSampler(serveExporter(t, "dcgm-exporter-mig.txt"), fakeProcs{
+		procs: map[string][]sampling.GPUProcess{
+			a100: {{PID: 31001}, {PID: 31002}, {PID: 31009}},
+		},
+		place: map[int]smi.MIGPlacement{
+			31001: {GPU: 0, GPUInstanceID: 1, ComputeInstanceID: 0},
+			31002: {GPU: 0, GPUInstanceID: 2, ComputeInstanceID: 0},
+		},
+	})
+	snap, err := s.Sample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]sampling.GPUSnapshot{}
+	for _, g := range snap.GPUs {
+		byID[g.ID()] = g
+	}
+	if len(byID) != 3 {
+		t.Fatalf("devices = %v, want 0/gi1, 0/gi2 and 0", snap.GPUs)
+	}
+	for id, want := range map[string]struct {
+		pid  int
+		busy float64
+	}{"0/gi1": {31001, 0}, "0/gi2": {31002, 73.4}} {
+		g := byID[id]
+		if g.MIG == nil || g.MIG.ParentUUID != a100 || g.UUID != "" {
+			t.Errorf("%s: MIG %+v UUID %q; want parent %s and no UUID", id, g.MIG, g.UUID, a100)
+		}
+		if len(g.ComputeProcs) != 1 || g.ComputeProcs[0].PID != want.pid {
+			t.Errorf("%s: procs %+v, want pid %d", id, g.ComputeProcs, want.pid)
+		}
+		if busy, ok := g.BusyPct(); !ok || busy < want.busy-0.01 || busy > want.busy+0.01 {
+			t.Errorf("%s: BusyPct = %v, %v; want %v", id, busy, ok, want.busy)
+		}
+	}
+	// A process whose instance is unknown stays visible but is never idle.
+	whole := byID["0"]
+	if len(whole.ComputeProcs) != 1 || whole.ComputeProcs[0].PID != 31009 {
+		t.Errorf("whole-GPU procs = %+v, want pid 31009", whole.ComputeProcs)
+	}
+	if _, ok := whole.BusyPct(); ok {
+		t.Error("whole-GPU entry of a MIG card has a known load")
+	}
+}
+
+func TestSampleRejectsAnotherNodesExporter(t *testing.T) {
+	const local = "GPU-0a1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9"
+	s := newSampler(serveExporter(t, "dcgm-exporter.txt"), fakeProcs{procs: map[string][]sampling.GPUProcess{
+		local: {{PID: 42}},
+	}})
+	_, err := s.Sample(context.Background())
+	if err == nil || !strings.Contains(err.Error(), local) {
+		t.Fatalf("err = %v, want local GPU %s not reported", err, local)
+	}
+}
+
+func TestSampleScrapeErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "exporter starting", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	_, err := newSampler(srv.URL, fakeProcs{}).Sample(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "exporter starting") {
+		t.Errorf("err = %v, want the 503 and its body", err)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="` + a100 + `"} 1` + "\n" + `DCGM_FI_DEV_FB_USED{gpu="0",UUID="` + a100 + "\n"))
+	}))
+	defer bad.Close()
+	if _, err := newSampler(bad.URL, fakeProcs{}).Sample(context.Background()); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want a parse error on line 2", err)
+	}
+}
+
+func TestRatioPct(t *testing.T) {
+	for in, want := range map[float64]float64{0: 0, 0.25: 25, 1: 100, 1.2: 100, -0.1: 0} {
+		if got := ratioPct(in); got != want {
+			t.Errorf("ratioPct(%v) = %v, want %v", in, got, want)
+		}
+	}
+	for in, want := range map[float64]uint32{0: 0, 37.4: 37, 37.5: 38, 101: 100, -3: 0} {
+		if got := pct(in); got != want {
+			t.Errorf("pct(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
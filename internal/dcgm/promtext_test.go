@@ -0,0 +1,47 @@
+package dcgm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	in := `# HELP DCGM_FI_DEV_GPU_UTIL GPU utilization (in %).
+# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-1",modelName="NVIDIA A100-SXM4-80GB"} 42
+
+DCGM_FI_DEV_FB_USED{ gpu="0" , UUID="GPU-1", } 1.5e3 1700000000000
+DCGM_FI_DEV_XID_ERRORS{gpu="0",UUID="GPU-1",err_msg="a \"quoted\" \\ path\nline"} 0
+up 1
+`
+	got, err := parseText(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sample{
+		{Name: "DCGM_FI_DEV_GPU_UTIL", Labels: map[string]string{"gpu": "0", "UUID": "GPU-1", "modelName": "NVIDIA A100-SXM4-80GB"}, Value: 42},
+		// Spaces, a trailing comma and a timestamp are accepted.
+		{Name: "DCGM_FI_DEV_FB_USED", Labels: map[string]string{"gpu": "0", "UUID": "GPU-1"}, Value: 1500},
+		{Name: "DCGM_FI_DEV_XID_ERRORS", Labels: map[string]string{"gpu": "0", "UUID": "GPU-1", "err_msg": "a \"quoted\" \\ path\nline"}, Value: 0},
+		{Name: "up", Value: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseText =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseTextErrors(t *testing.T) {
+	for name, in := range map[string]string{
+		"no value":           `DCGM_FI_DEV_GPU_UTIL{gpu="0"}`,
+		"bad value":          `DCGM_FI_DEV_GPU_UTIL{gpu="0"} busy`,
+		"unterminated":       `DCGM_FI_DEV_GPU_UTIL{gpu="0" 1`,
+		"unquoted":           `DCGM_FI_DEV_GPU_UTIL{gpu=0} 1`,
+		"unterminated value": `DCGM_FI_DEV_GPU_UTIL{gpu="0} 1`,
+		"no name":            `{gpu="0"} 1`,
+	} {
+		if _, err := parseText(strings.NewReader(in + "\n")); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
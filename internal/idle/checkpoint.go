@@ -0,0 +1,174 @@
+package idle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckpointVersion is bumped whenever the on-disk layout changes
+// incompatibly; older or newer files are ignored rather than misread.
+const CheckpointVersion = 1
+
+// The on-disk types are separate from the in-memory ones so the tracker can
+// evolve without silently changing the file format.
+type checkpointFile struct {
+	Version int               `json:"version"`
+	SavedAt time.Time         `json:"savedAt"`
+	States  []checkpointState `json:"states"`
+}
+
+type checkpointState struct {
+	UID         string `json:"podUID,omitempty"`
+	Namespace   string `json:"podNamespace,omitempty"`
+	Name        string `json:"podName,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
+
+	IdleCount  int       `json:"idleCount"`
+	IdleSince  time.Time `json:"idleSince"`
+	LastSeen   time.Time `json:"lastSeen"`
+	LastActive time.Time `json:"lastActive"`
+	Reported   bool      `json:"reported"`
+
+	PolicyName             string `json:"policyName,omitempty"`
+	IdleMinutes            int    `json:"idleMinutes,omitempty"`
+	ConsecutiveIdleSamples int    `json:"consecutiveIdleSamples,omitempty"`
+	UtilThresholdPct       int    `json:"utilThresholdPercent,omitempty"`
+
+	GPUs        []int    `json:"gpus,omitempty"`
+	Devices     []string `json:"devices,omitempty"`
+	PIDs        []int    `json:"pids,omitempty"`
+	Cmdlines    []string `json:"cmdlines,omitempty"`
+	UtilSamples int      `json:"utilSamples,omitempty"`
+	Signal      string   `json:"signal,omitempty"`
+}
+
+// SaveCheckpoint atomically writes the tracker state to path: the data goes
+// to a temp file in the same directory which is fsynced and renamed over
+// path, so a crash mid-write leaves the previous checkpoint intact.
+func (t *Tracker) SaveCheckpoint(path string, now time.Time) error {
+	f := checkpointFile{Version: CheckpointVersion, SavedAt: now.UTC(), States: make([]checkpointState, 0, len(t.states))}
+	for _, st := range t.states {
+		f.States = append(f.States, toCheckpoint(st))
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// LoadCheckpoint restores states saved by SaveCheckpoint. keep decides for
+// each saved state whether it still describes a live pod/process, and may
+// adjust it before it is restored; rejected states are dropped. A missing
+// file is not an error.
+func (t *Tracker) LoadCheckpoint(path string, keep func(*PodState) bool) (restored, dropped int, err error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var f checkpointFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return 0, 0, fmt.Errorf("decode checkpoint: %w", err)
+	}
+	if f.Version != CheckpointVersion {
+		return 0, len(f.States), fmt.Errorf("checkpoint version %d not supported (want %d)", f.Version, CheckpointVersion)
+	}
+	for _, cs := range f.States {
+		st := fromCheckpoint(cs)
+		if keep != nil && !keep(st) {
+			dropped++
+			continue
+		}
+		t.states[keyString(st.Key)] = st
+		restored++
+	}
+	return restored, dropped, nil
+}
+
+func toCheckpoint(st *PodState) checkpointState {
+	return checkpointState{
+		UID:                    st.Key.UID,
+		Namespace:              st.Key.Namespace,
+		Name:                   st.Key.Name,
+		ContainerID:            st.Key.ContainerID,
+		IdleCount:              st.IdleCount,
+		IdleSince:              st.IdleSince,
+		LastSeen:               st.LastSeen,
+		LastActive:             st.LastActive,
+		Reported:               st.Reported,
+		PolicyName:             st.Policy.Name,
+		IdleMinutes:            st.Policy.IdleMinutes,
+		ConsecutiveIdleSamples: st.Policy.ConsecutiveIdleSamples,
+		UtilThresholdPct:       st.Policy.UtilThresholdPct,
+		GPUs:                   st.LastEvidence.GPUs,
+		Devices:                st.LastEvidence.Devices,
+		PIDs:                   st.LastEvidence.PIDs,
+		Cmdlines:               st.LastEvidence.Cmdlines,
+		UtilSamples:            st.LastEvidence.UtilSamples,
+		Signal:                 st.LastEvidence.Signal,
+	}
+}
+
+func fromCheckpoint(cs checkpointState) *PodState {
+	st := &PodState{
+		Key:        PodKey{UID: cs.UID, Namespace: cs.Namespace, Name: cs.Name, ContainerID: cs.ContainerID},
+		IdleCount:  cs.IdleCount,
+		IdleSince:  cs.IdleSince,
+		LastSeen:   cs.LastSeen,
+		LastActive: cs.LastActive,
+		Reported:   cs.Reported,
+		Policy: Policy{
+			Name:                   cs.PolicyName,
+			IdleMinutes:            cs.IdleMinutes,
+			ConsecutiveIdleSamples: cs.ConsecutiveIdleSamples,
+			UtilThresholdPct:       cs.UtilThresholdPct,
+		},
+	}
+	if cs.IdleCount > 0 {
+		st.LastEvidence = PodEvidence{
+			GPUs:        cs.GPUs,
+			Devices:     cs.Devices,
+			PIDs:        cs.PIDs,
+			Cmdlines:    cs.Cmdlines,
+			UtilSamples: cs.UtilSamples,
+			IdleSince:   cs.IdleSince,
+			Policy:      cs.PolicyName,
+			Signal:      cs.Signal,
+		}
+	}
+	return st
+}
@@ -0,0 +1,174 @@
+package idle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// trackerWithStates has one pod idle for ten samples and one active pod.
+func trackerWithStates() *Tracker {
+	tr := NewTracker(30, 30, time.Minute)
+	idlePod := PodKey{UID: "uid-idle", Namespace: "team-a", Name: "train-0", ContainerID: "c1"}
+	busyPod := PodKey{UID: "uid-busy", Namespace: "team-b", Name: "infer-0"}
+	for m := 0; m < 10; m++ {
+		at := t0.Add(time.Duration(m) * time.Minute)
+		tr.Observe(Observation{
+			Key: idlePod, SeenAt: at, Idle: true,
+			GPUs: []int{0}, Devices: []string{"GPU-0"}, PIDs: []int{42}, Cmdlines: []string{"python train.py"},
+			Policy: Policy{Name: "dev", IdleMinutes: 120, UtilThresholdPct: 5},
+		})
+		tr.Observe(Observation{Key: busyPod, SeenAt: at, Idle: false, GPUs: []int{1}, PIDs: []int{7}})
+	}
+	return tr
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	saved := trackerWithStates()
+	if err := saved.SaveCheckpoint(path, t0.Add(10*time.Minute)); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+
+	loaded := NewTracker(30, 30, time.Minute)
+	restored, dropped, err := loaded.LoadCheckpoint(path, nil)
+	if err != nil || restored != 2 || dropped != 0 {
+		t.Fatalf("LoadCheckpoint = %d, %d, %v; want 2, 0, nil", restored, dropped, err)
+	}
+	if !reflect.DeepEqual(loaded.states, saved.states) {
+		for k, st := range saved.states {
+			t.Errorf("%s:\n saved    %+v\n restored %+v", k, *st, loaded.states[k])
+		}
+	}
+
+	st := loaded.states["uid:uid-idle"]
+	if st.IdleCount != 10 || !st.IdleSince.Equal(t0) {
+		t.Errorf("idle pod restored with count %d since %v", st.IdleCount, st.IdleSince)
+	}
+}
+
+func TestLoadCheckpointKeep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := trackerWithStates().SaveCheckpoint(path, t0); err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTracker(30, 30, time.Minute)
+	restored, dropped, err := tr.LoadCheckpoint(path, func(st *PodState) bool {
+		if st.IdleCount == 0 {
+			return false
+		}
+		st.IdleSince = st.IdleSince.Add(time.Minute)
+		return true
+	})
+	if err != nil || restored != 1 || dropped != 1 {
+		t.Fatalf("LoadCheckpoint = %d, %d, %v; want 1, 1, nil", restored, dropped, err)
+	}
+	if st := tr.states["uid:uid-idle"]; st == nil || !st.IdleSince.Equal(t0.Add(time.Minute)) {
+		t.Errorf("keep's adjustment was not restored: %+v", st)
+	}
+}
+
+func TestLoadCheckpointRejectsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	if err := trackerWithStates().SaveCheckpoint(good, t0); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"newer version", strings.Replace(string(b), `"version":1`, `"version":2`, 1), "checkpoint version 2 not supported"},
+		{"older version", strings.Replace(string(b), `"version":1`, `"version":0`, 1), "checkpoint version 0 not supported"},
+		{"truncated", string(b[:len(b)/2]), "decode checkpoint"},
+		{"empty", "", "decode checkpoint"},
+		{"not json", "version: 1\n", "decode checkpoint"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(c.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(c.body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			tr := NewTracker(30, 30, time.Minute)
+			restored, _, err := tr.LoadCheckpoint(path, nil)
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("err = %v, want %q", err, c.wantErr)
+			}
+			if restored != 0 || len(tr.states) != 0 {
+				t.Errorf("restored %d states from a rejected file", len(tr.states))
+			}
+		})
+	}
+}
+
+func TestLoadCheckpointMissingFile(t *testing.T) {
+	tr := NewTracker(30, 30, time.Minute)
+	restored, dropped, err := tr.LoadCheckpoint(filepath.Join(t.TempDir(), "none.json"), nil)
+	if err != nil || restored != 0 || dropped != 0 {
+		t.Errorf("LoadCheckpoint = %d, %d, %v; want 0, 0, nil", restored, dropped, err)
+	}
+}
+
+func TestSaveCheckpointReplacesFileByRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A second link to the old inode sees it unchanged only if the new
+	// checkpoint was renamed over path rather than written into it.
+	old := filepath.Join(dir, "old-link")
+	if err := os.Link(path, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trackerWithStates().SaveCheckpoint(path, t0); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if b, err := os.ReadFile(old); err != nil || string(b) != "previous" {
+		t.Errorf("old checkpoint = %q, %v; was written in place", b, err)
+	}
+	if b, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(b), `{"version":1,`) {
+		t.Errorf("new checkpoint = %q, %v", b, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestSaveCheckpointFailedRenameLeavesNoTemp(t *testing.T) {
+	dir := t.TempDir()
+	// A non-empty directory at path cannot be renamed over.
+	path := filepath.Join(dir, "state.json")
+	if err := os.MkdirAll(filepath.Join(path, "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := trackerWithStates().SaveCheckpoint(path, t0); err == nil {
+		t.Fatal("SaveCheckpoint over a directory succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries after a failed save, want only %s", len(entries), filepath.Base(path))
+	}
+}
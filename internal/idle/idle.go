@@ -9,12 +9,30 @@ type PodKey struct {
 	ContainerID string
 }
 
+// Policy is the idle rule a pod is judged against. Pods in different
+// namespaces may resolve to different policies (PRD FR-14).
+type Policy struct {
+	Name                   string
+	IdleMinutes            int
+	ConsecutiveIdleSamples int
+	UtilThresholdPct       int
+	// ReclaimTarget is a config.ReclaimTarget* value.
+	ReclaimTarget string
+}
+
 type PodEvidence struct {
-	GPUs        []int
+	GPUs []int
+	// Devices are the GPUs or MIG slices (sampling.GPUSnapshot.ID) the pod
+	// was judged on.
+	Devices     []string
 	PIDs        []int
 	Cmdlines    []string
 	UtilSamples int
 	IdleSince   time.Time
+	Policy      string
+	// Signal tells whether idleness was judged on the pod's own processes
+	// or on whole-card utilization (Signal* constants).
+	Signal string
 }
 
 type PodState struct {
@@ -24,6 +42,7 @@ type PodState struct {
 	LastSeen   time.Time
 	LastActive time.Time
 	Reported   bool
+	Policy     Policy
 
 	LastEvidence PodEvidence
 }
@@ -61,14 +80,35 @@ type Observation struct {
 	SeenAt   time.Time
 	Idle     bool
 	GPUs     []int
+	Devices  []string
 	PIDs     []int
 	Cmdlines []string
+	// Policy resolved for the pod; zero fields fall back to the tracker's
+	// global settings.
+	Policy Policy
+	// Usage, when set, lets the tracker judge idleness itself against
+	// Policy.UtilThresholdPct (see IsIdle); Idle is then ignored.
+	Usage []GPUUsage
 }
 
 type Candidate struct {
 	Key      PodKey
 	Evidence PodEvidence
 	IdleFor  time.Duration
+	Policy   Policy
+}
+
+func (t *Tracker) effectivePolicy(p Policy) Policy {
+	if p.IdleMinutes <= 0 {
+		p.IdleMinutes = t.IdleMinutes
+	}
+	if p.ConsecutiveIdleSamples <= 0 {
+		p.ConsecutiveIdleSamples = t.ConsecutiveIdleSamples
+	}
+	if p.Name == "" {
+		p.Name = "default"
+	}
+	return p
 }
 
 func (t *Tracker) Observe(obs Observation) (cand *Candidate) {
@@ -79,18 +119,27 @@ func (t *Tracker) Observe(obs Observation) (cand *Candidate) {
 		t.states[ks] = st
 	}
 	st.LastSeen = obs.SeenAt
+	st.Policy = t.effectivePolicy(obs.Policy)
 
-	if obs.Idle {
+	idleNow := obs.Idle
+	if len(obs.Usage) > 0 {
+		idleNow = IsIdle(obs.Usage, st.Policy.UtilThresholdPct)
+	}
+
+	if idleNow {
 		st.IdleCount++
 		if st.IdleCount == 1 {
 			st.IdleSince = obs.SeenAt
 		}
 		st.LastEvidence = PodEvidence{
 			GPUs:        append([]int(nil), obs.GPUs...),
+			Devices:     append([]string(nil), obs.Devices...),
 			PIDs:        append([]int(nil), obs.PIDs...),
 			Cmdlines:    append([]string(nil), obs.Cmdlines...),
 			UtilSamples: st.IdleCount,
 			IdleSince:   st.IdleSince,
+			Policy:      st.Policy.Name,
+			Signal:      usageSignal(obs.Usage),
 		}
 	} else {
 		st.IdleCount = 0
@@ -100,10 +149,10 @@ func (t *Tracker) Observe(obs Observation) (cand *Candidate) {
 		st.LastEvidence = PodEvidence{}
 	}
 
-	if !st.Reported && st.IdleCount >= t.ConsecutiveIdleSamples && !st.IdleSince.IsZero() {
+	if !st.Reported && st.IdleCount >= st.Policy.ConsecutiveIdleSamples && !st.IdleSince.IsZero() {
 		idleFor := obs.SeenAt.Sub(st.IdleSince)
-		if idleFor >= time.Duration(t.IdleMinutes)*time.Minute {
-			c := Candidate{Key: st.Key, Evidence: st.LastEvidence, IdleFor: idleFor}
+		if idleFor >= time.Duration(st.Policy.IdleMinutes)*time.Minute {
+			c := Candidate{Key: st.Key, Evidence: st.LastEvidence, IdleFor: idleFor, Policy: st.Policy}
 			st.Reported = true
 			return &c
 		}
@@ -111,6 +160,15 @@ func (t *Tracker) Observe(obs Observation) (cand *Candidate) {
 	return nil
 }
 
+// Unreport re-arms a reported pod so its next idle observation returns it
+// as a candidate again. Use it when no final decision could be made on the
+// candidate, e.g. because the metadata it needs was not available yet.
+func (t *Tracker) Unreport(k PodKey) {
+	if st := t.states[keyString(k)]; st != nil {
+		st.Reported = false
+	}
+}
+
 func (t *Tracker) GC(now time.Time, maxAge time.Duration) {
 	for k, st := range t.states {
 		if now.Sub(st.LastSeen) > maxAge {
@@ -118,3 +176,15 @@ func (t *Tracker) GC(now time.Time, maxAge time.Duration) {
 		}
 	}
 }
+
+// ReportedCount returns how many pods observed at seenAt are currently past
+// the idle threshold (reported and not active since).
+func (t *Tracker) ReportedCount(seenAt time.Time) int {
+	n := 0
+	for _, st := range t.states {
+		if st.Reported && st.LastSeen.Equal(seenAt) {
+			n++
+		}
+	}
+	return n
+}
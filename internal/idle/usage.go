@@ -0,0 +1,71 @@
+package idle
+
+// GPUUsage is what one pod was seen doing on one GPU in a sample.
+type GPUUsage struct {
+	GPU int
+	// Device is sampling.GPUSnapshot.ID: the GPU or MIG slice judged.
+	Device string
+	// CardBusyPct is the whole card's busy percentage
+	// (sampling.GPUSnapshot.BusyPct); only valid when CardKnown.
+	CardBusyPct float64
+	CardKnown   bool
+	// ProcBusyPct is the summed SM utilization of the pod's own processes
+	// on this GPU; only valid when ProcKnown, i.e. every one of them had a
+	// per-process reading.
+	ProcBusyPct float64
+	ProcKnown   bool
+}
+
+// Usage signals recorded in PodEvidence.Signal.
+const (
+	SignalProcess = "process"
+	SignalCard    = "card"
+	SignalMixed   = "mixed"
+)
+
+// BusyPct is the pod's own load on the GPU when known, else the card's: on a
+// time-sliced GPU a busy neighbour must not keep an idle pod alive, but
+// without per-process data the card is the only safe upper bound. ok is
+// false when neither is known.
+func (u GPUUsage) BusyPct() (pct float64, ok bool) {
+	if u.ProcKnown {
+		return u.ProcBusyPct, true
+	}
+	return u.CardBusyPct, u.CardKnown
+}
+
+// IsIdle reports whether a pod is idle on every GPU it uses, judged against
+// thresholdPct (busy < threshold counts as idle). No usage, or a GPU whose
+// load is unknown, is not idle: an unreadable counter must never start the
+// idle clock.
+func IsIdle(usage []GPUUsage, thresholdPct int) bool {
+	if len(usage) == 0 {
+		return false
+	}
+	for _, u := range usage {
+		busy, ok := u.BusyPct()
+		if !ok || busy >= float64(thresholdPct) {
+			return false
+		}
+	}
+	return true
+}
+
+func usageSignal(usage []GPUUsage) string {
+	if len(usage) == 0 {
+		return ""
+	}
+	proc := 0
+	for _, u := range usage {
+		if u.ProcKnown {
+			proc++
+		}
+	}
+	switch proc {
+	case len(usage):
+		return SignalProcess
+	case 0:
+		return SignalCard
+	}
+	return SignalMixed
+}
@@ -0,0 +1,88 @@
+package idle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsIdle(t *testing.T) {
+	cases := []struct {
+		name  string
+		usage []GPUUsage
+		want  bool
+	}{
+		{"no usage", nil, false},
+		{"idle process on a busy shared card", []GPUUsage{{CardBusyPct: 90, CardKnown: true, ProcBusyPct: 0, ProcKnown: true}}, true},
+		{"busy process on a quiet card", []GPUUsage{{CardBusyPct: 0, CardKnown: true, ProcBusyPct: 40, ProcKnown: true}}, false},
+		{"process at the threshold", []GPUUsage{{CardBusyPct: 0, CardKnown: true, ProcBusyPct: 5, ProcKnown: true}}, false},
+		{"process reading without card", []GPUUsage{{ProcBusyPct: 0, ProcKnown: true}}, true},
+		{"unknown process on a busy card", []GPUUsage{{CardBusyPct: 90, CardKnown: true}}, false},
+		{"unknown process on a quiet card", []GPUUsage{{CardBusyPct: 2, CardKnown: true}}, true},
+		{"nothing known", []GPUUsage{{}}, false},
+		{"idle on one GPU, busy on another", []GPUUsage{
+			{GPU: 0, ProcKnown: true},
+			{GPU: 1, ProcBusyPct: 30, ProcKnown: true},
+		}, false},
+		{"idle on one GPU, unknown on another", []GPUUsage{
+			{GPU: 0, ProcKnown: true},
+			{GPU: 1},
+		}, false},
+		{"idle everywhere", []GPUUsage{
+			{GPU: 0, CardBusyPct: 80, CardKnown: true, ProcKnown: true},
+			{GPU: 1, CardBusyPct: 1, CardKnown: true},
+		}, true},
+	}
+	for _, c := range cases {
+		if got := IsIdle(c.usage, 5); got != c.want {
+			t.Errorf("%s: IsIdle = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestUsageSignal(t *testing.T) {
+	proc := GPUUsage{ProcKnown: true, CardKnown: true}
+	card := GPUUsage{CardKnown: true}
+	cases := []struct {
+		usage []GPUUsage
+		want  string
+	}{
+		{nil, ""},
+		{[]GPUUsage{proc, proc}, SignalProcess},
+		{[]GPUUsage{card}, SignalCard},
+		{[]GPUUsage{proc, card}, SignalMixed},
+	}
+	for _, c := range cases {
+		if got := usageSignal(c.usage); got != c.want {
+			t.Errorf("usageSignal(%+v) = %q, want %q", c.usage, got, c.want)
+		}
+	}
+}
+
+func TestObserveJudgesOwnLoad(t *testing.T) {
+	tr := NewTracker(2, 3, time.Minute)
+	key := PodKey{UID: "uid-a"}
+	// Idle is ignored once Usage is set: the busy card must not count.
+	obs := func(m int, u GPUUsage) *Candidate {
+		return tr.Observe(Observation{Key: key, SeenAt: t0.Add(time.Duration(m) * time.Minute), Idle: false, Usage: []GPUUsage{u},
+			Policy: Policy{UtilThresholdPct: 5}})
+	}
+	shared := GPUUsage{CardBusyPct: 95, CardKnown: true, ProcKnown: true}
+	for m := 0; m < 2; m++ {
+		if c := obs(m, shared); c != nil {
+			t.Fatalf("candidate after %d samples", m+1)
+		}
+	}
+	c := obs(2, shared)
+	if c == nil || c.Evidence.Signal != SignalProcess {
+		t.Fatalf("candidate = %+v, want one on the process signal", c)
+	}
+
+	// Without a process reading the busy card decides and resets the window.
+	tr.Unreport(key)
+	if c := obs(3, GPUUsage{CardBusyPct: 95, CardKnown: true}); c != nil {
+		t.Fatalf("candidate on a busy card without a process reading: %+v", c)
+	}
+	if st := tr.states["uid:uid-a"]; st.IdleCount != 0 {
+		t.Errorf("IdleCount = %d after falling back to the busy card, want 0", st.IdleCount)
+	}
+}
@@ -0,0 +1,161 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client is a minimal Kubernetes REST client. We only need a handful of core
+// endpoints, so this avoids pulling client-go into a node agent.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	// TokenFile is re-read per request because projected service account
+	// tokens are rotated by the kubelet.
+	TokenFile string
+	Token     string
+}
+
+const (
+	saTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	saCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+var ErrNotInCluster = errors.New("not running in a kubernetes cluster (KUBERNETES_SERVICE_HOST unset)")
+
+// NewClient builds a client for an arbitrary API server URL (e.g. an
+// httptest server in tests).
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: httpClient}
+}
+
+// InClusterClient builds a client from the pod's service account.
+func InClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	caPEM, err := os.ReadFile(saCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read service account ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in service account ca.crt")
+	}
+	if _, err := os.Stat(saTokenFile); err != nil {
+		return nil, fmt.Errorf("service account token: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	// No overall timeout: watches are long-lived. Requests carry contexts.
+	c := NewClient("https://"+net.JoinHostPort(host, port), &http.Client{Transport: transport})
+	c.TokenFile = saTokenFile
+	return c, nil
+}
+
+// StatusError is returned for non-2xx responses.
+type StatusError struct {
+	Code    int
+	Method  string
+	Path    string
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kube api %s %s: %d %s", e.Method, e.Path, e.Code, e.Message)
+}
+
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tok := c.bearerToken(); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	return req, nil
+}
+
+func (c *Client) bearerToken() string {
+	if c.TokenFile != "" {
+		if b, err := os.ReadFile(c.TokenFile); err == nil {
+			return strings.TrimSpace(string(b))
+		}
+	}
+	return c.Token
+}
+
+// Do sends a JSON request and decodes a JSON response into out (if non-nil).
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, method, path); err != nil {
+		return err
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func checkStatus(resp *http.Response, method, path string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(b))
+	var st struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(b, &st) == nil && st.Message != "" {
+		msg = st.Message
+	}
+	return &StatusError{Code: resp.StatusCode, Method: method, Path: path, Message: msg}
+}
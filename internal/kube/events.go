@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ObjectMeta struct {
+	Name              string            `json:"name,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	UID               string            `json:"uid,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	CreationTimestamp string            `json:"creationTimestamp,omitempty"`
+}
+
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	UID        string `json:"uid,omitempty"`
+}
+
+type EventSource struct {
+	Component string `json:"component,omitempty"`
+	Host      string `json:"host,omitempty"`
+}
+
+// Event mirrors the core/v1 Event fields we set.
+type Event struct {
+	APIVersion          string          `json:"apiVersion"`
+	Kind                string          `json:"kind"`
+	Metadata            ObjectMeta      `json:"metadata"`
+	InvolvedObject      ObjectReference `json:"involvedObject"`
+	Reason              string          `json:"reason"`
+	Message             string          `json:"message"`
+	Type                string          `json:"type"`
+	Source              EventSource     `json:"source"`
+	FirstTimestamp      string          `json:"firstTimestamp"`
+	LastTimestamp       string          `json:"lastTimestamp"`
+	Count               int32           `json:"count"`
+	ReportingController string          `json:"reportingComponent,omitempty"`
+	ReportingInstance   string          `json:"reportingInstance,omitempty"`
+}
+
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// EventRecorder posts core/v1 Events so reclaim decisions show up in
+// `kubectl describe pod`.
+type EventRecorder struct {
+	client    *Client
+	component string
+	host      string
+	now       func() time.Time
+}
+
+func NewEventRecorder(c *Client, component, host string) *EventRecorder {
+	return &EventRecorder{client: c, component: component, host: host, now: time.Now}
+}
+
+// PodWarning posts a Warning Event on the given pod.
+func (r *EventRecorder) PodWarning(ctx context.Context, namespace, name, uid, reason, message string) error {
+	if namespace == "" || name == "" {
+		return fmt.Errorf("pod namespace/name unknown (uid=%q)", uid)
+	}
+	now := r.now().UTC()
+	ts := now.Format(time.RFC3339)
+	ev := Event{
+		APIVersion: "v1",
+		Kind:       "Event",
+		Metadata: ObjectMeta{
+			// Same convention as client-go's recorder: <object>.<unique hex>.
+			Name:      fmt.Sprintf("%s.%x", name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  namespace,
+			Name:       name,
+			UID:        uid,
+		},
+		Reason:              reason,
+		Message:             truncate(message, 1024),
+		Type:                EventTypeWarning,
+		Source:              EventSource{Component: r.component, Host: r.host},
+		FirstTimestamp:      ts,
+		LastTimestamp:       ts,
+		Count:               1,
+		ReportingController: r.component,
+		ReportingInstance:   r.component + "-" + r.host,
+	}
+	path := "/api/v1/namespaces/" + namespace + "/events"
+	return r.client.Do(ctx, http.MethodPost, path, nil, ev, nil)
+}
+
+// truncate shortens s to at most max bytes, cutting on a rune boundary so
+// the API server does not reject the Event as invalid UTF-8.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - 3
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut]) + "..."
+}
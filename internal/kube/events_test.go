@@ -0,0 +1,99 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestPodWarningPostsEvent(t *testing.T) {
+	var (
+		gotMethod, gotPath, gotAuth string
+		got                         Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotAuth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, srv.Client())
+	c.Token = "tok"
+	rec := NewEventRecorder(c, "gpu-reclaimer-agent", "node-a")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec.now = func() time.Time { return now }
+
+	err := rec.PodWarning(context.Background(), "team-a", "train-0", "uid-1", "GPUReclaimed", "killed pids [42] on GPU 0")
+	if err != nil {
+		t.Fatalf("PodWarning: %v", err)
+	}
+
+	if gotMethod != http.MethodPost || gotPath != "/api/v1/namespaces/team-a/events" {
+		t.Errorf("request = %s %s", gotMethod, gotPath)
+	}
+	if gotAuth != "Bearer tok" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	wantObj := ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "team-a", Name: "train-0", UID: "uid-1"}
+	if got.InvolvedObject != wantObj {
+		t.Errorf("involvedObject = %+v, want %+v", got.InvolvedObject, wantObj)
+	}
+	if got.Reason != "GPUReclaimed" || got.Message != "killed pids [42] on GPU 0" || got.Type != EventTypeWarning {
+		t.Errorf("reason/message/type = %q/%q/%q", got.Reason, got.Message, got.Type)
+	}
+	if got.Metadata.Namespace != "team-a" || !strings.HasPrefix(got.Metadata.Name, "train-0.") {
+		t.Errorf("metadata = %+v", got.Metadata)
+	}
+	if got.FirstTimestamp != "2026-01-02T03:04:05Z" || got.Count != 1 {
+		t.Errorf("firstTimestamp/count = %q/%d", got.FirstTimestamp, got.Count)
+	}
+	if got.Source.Component != "gpu-reclaimer-agent" || got.Source.Host != "node-a" {
+		t.Errorf("source = %+v", got.Source)
+	}
+}
+
+func TestPodWarningErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"events is forbidden"}`))
+	}))
+	defer srv.Close()
+	rec := NewEventRecorder(NewClient(srv.URL, srv.Client()), "gpu-reclaimer-agent", "node-a")
+
+	if err := rec.PodWarning(context.Background(), "", "", "uid-1", "R", "m"); err == nil {
+		t.Error("expected an error for an unknown pod name")
+	}
+	err := rec.PodWarning(context.Background(), "team-a", "train-0", "uid-1", "R", "m")
+	if se, ok := err.(*StatusError); !ok || se.Code != http.StatusForbidden || se.Message != "events is forbidden" {
+		t.Errorf("err = %v, want 403 StatusError", err)
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	long := strings.Repeat("x", 2000)
+	if got := truncate(long, 1024); len(got) != 1024 || !strings.HasSuffix(got, "...") {
+		t.Errorf("truncate len = %d", len(got))
+	}
+	if got := truncate("short", 1024); got != "short" {
+		t.Errorf("truncate = %q", got)
+	}
+	// Multi-byte runes straddling the cut are dropped whole.
+	for _, s := range []string{strings.Repeat("空", 500), "x" + strings.Repeat("空", 500), "xx" + strings.Repeat("😀", 400)} {
+		got := truncate(s, 1024)
+		if len(got) > 1024 || !utf8.ValidString(got) || !strings.HasSuffix(got, "...") {
+			t.Errorf("truncate(%q...) = len %d, valid UTF-8 %v", s[:6], len(got), utf8.ValidString(got))
+		}
+		if len(got) < 1024-3-utf8.UTFMax {
+			t.Errorf("truncate cut %d bytes more than needed", 1024-len(got))
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Object is the metadata-only view of any API object; the agent only needs
+// names, UIDs, labels and annotations.
+type Object struct {
+	Metadata ObjectMeta `json:"metadata"`
+}
+
+type objectList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []Object `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// Informer keeps a list-watch cache of one resource collection, optionally
+// narrowed by a field selector (e.g. pods on this node).
+type Informer struct {
+	client        *Client
+	path          string
+	fieldSelector string
+
+	mu     sync.RWMutex
+	items  map[string]Object // namespace/name
+	byUID  map[string]string // uid -> namespace/name
+	synced bool
+
+	handlersMu sync.Mutex
+	handlers   []func(obj Object, deleted bool)
+
+	// First delay after a failed list or watch; doubles up to a minute.
+	minBackoff time.Duration
+}
+
+var errWatchExpired = errors.New("watch resource version expired")
+
+func NewInformer(c *Client, path, fieldSelector string) *Informer {
+	return &Informer{
+		client:        c,
+		path:          path,
+		fieldSelector: fieldSelector,
+		items:         map[string]Object{},
+		byUID:         map[string]string{},
+		minBackoff:    time.Second,
+	}
+}
+
+// OnChange registers a callback invoked for every add/update/delete,
+// including the items of each (re)list. Register before Run.
+func (i *Informer) OnChange(fn func(obj Object, deleted bool)) {
+	i.handlersMu.Lock()
+	defer i.handlersMu.Unlock()
+	i.handlers = append(i.handlers, fn)
+}
+
+// Run lists and watches until ctx is done. A watch the server ends cleanly
+// is resumed from the last resourceVersion it delivered; an expired one
+// (410 Gone) is relisted at once, any other error relisted with backoff.
+func (i *Informer) Run(ctx context.Context) {
+	backoff := i.minBackoff
+	rv := ""
+	for ctx.Err() == nil {
+		var err error
+		if rv == "" {
+			rv, err = i.list(ctx)
+			if err == nil {
+				backoff = i.minBackoff
+			}
+		}
+		if err == nil {
+			rv, err = i.watch(ctx, rv)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			rv = ""
+		}
+		if err != nil && !errors.Is(err, errWatchExpired) {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func (i *Informer) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.synced
+}
+
+func (i *Informer) Get(namespace, name string) (Object, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	o, ok := i.items[objKey(namespace, name)]
+	return o, ok
+}
+
+func (i *Informer) GetByUID(uid string) (Object, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	k, ok := i.byUID[uid]
+	if !ok {
+		return Object{}, false
+	}
+	o, ok := i.items[k]
+	return o, ok
+}
+
+func (i *Informer) List() []Object {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	out := make([]Object, 0, len(i.items))
+	for _, o := range i.items {
+		out = append(out, o)
+	}
+	return out
+}
+
+func (i *Informer) query() url.Values {
+	q := url.Values{}
+	if i.fieldSelector != "" {
+		q.Set("fieldSelector", i.fieldSelector)
+	}
+	return q
+}
+
+func (i *Informer) list(ctx context.Context) (string, error) {
+	lctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var l objectList
+	if err := i.client.Do(lctx, http.MethodGet, i.path, i.query(), nil, &l); err != nil {
+		return "", err
+	}
+
+	items := make(map[string]Object, len(l.Items))
+	byUID := make(map[string]string, len(l.Items))
+	for _, o := range l.Items {
+		k := objKey(o.Metadata.Namespace, o.Metadata.Name)
+		items[k] = o
+		byUID[o.Metadata.UID] = k
+	}
+	i.mu.Lock()
+	old := i.items
+	i.items, i.byUID, i.synced = items, byUID, true
+	i.mu.Unlock()
+
+	for k, o := range old {
+		if _, ok := items[k]; !ok {
+			i.notify(o, true)
+		}
+	}
+	for _, o := range l.Items {
+		i.notify(o, false)
+	}
+	return l.Metadata.ResourceVersion, nil
+}
+
+// watch applies events from rv on and returns the resourceVersion to resume
+// from, which is rv advanced by every event and bookmark received.
+func (i *Informer) watch(ctx context.Context, rv string) (string, error) {
+	q := i.query()
+	q.Set("watch", "1")
+	q.Set("resourceVersion", rv)
+	q.Set("allowWatchBookmarks", "true")
+	q.Set("timeoutSeconds", "300")
+
+	req, err := i.client.newRequest(ctx, http.MethodGet, i.path, q, nil)
+	if err != nil {
+		return rv, err
+	}
+	resp, err := i.client.HTTP.Do(req)
+	if err != nil {
+		return rv, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, http.MethodGet, i.path); err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.Code == http.StatusGone {
+			return rv, errWatchExpired
+		}
+		return rv, err
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev watchEvent
+		if err := dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				// Server-side timeout: resume where the stream ended.
+				return rv, nil
+			}
+			// A garbled or cut-off stream; relisting right away could
+			// hit the same fault in a tight loop.
+			return rv, fmt.Errorf("watch %s: decode event: %w", i.path, err)
+		}
+		switch ev.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			var o Object
+			if err := json.Unmarshal(ev.Object, &o); err != nil {
+				return rv, fmt.Errorf("watch %s: decode %s object: %w", i.path, ev.Type, err)
+			}
+			i.apply(o, ev.Type == "DELETED")
+			if o.Metadata.ResourceVersion != "" {
+				rv = o.Metadata.ResourceVersion
+			}
+		case "BOOKMARK":
+			var o Object
+			if json.Unmarshal(ev.Object, &o) == nil && o.Metadata.ResourceVersion != "" {
+				rv = o.Metadata.ResourceVersion
+			}
+		case "ERROR":
+			var st struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(ev.Object, &st)
+			if st.Code == http.StatusGone {
+				return rv, errWatchExpired
+			}
+			return rv, fmt.Errorf("watch %s: %d %s", i.path, st.Code, st.Message)
+		}
+	}
+}
+
+func (i *Informer) apply(o Object, deleted bool) {
+	k := objKey(o.Metadata.Namespace, o.Metadata.Name)
+	i.mu.Lock()
+	if deleted {
+		delete(i.items, k)
+		delete(i.byUID, o.Metadata.UID)
+	} else {
+		i.items[k] = o
+		i.byUID[o.Metadata.UID] = k
+	}
+	i.mu.Unlock()
+	i.notify(o, deleted)
+}
+
+func (i *Informer) notify(o Object, deleted bool) {
+	i.handlersMu.Lock()
+	hs := append([]func(Object, bool){}, i.handlers...)
+	i.handlersMu.Unlock()
+	for _, h := range hs {
+		h(o, deleted)
+	}
+}
+
+func objKey(namespace, name string) string {
+	return namespace + "/" + name
+}
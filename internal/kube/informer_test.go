@@ -0,0 +1,205 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI serves one collection for an Informer: each list returns the next
+// of lists (the last one repeats), each watch streams the next of watches
+// and then ends; once they run out a watch stays open until the client
+// leaves. A watch body "status:<code>" fails the request with that code.
+type fakeAPI struct {
+	mu       sync.Mutex
+	lists    []string
+	watches  []string
+	requests []string // "list" or "watch@<resourceVersion>", in order
+	at       []time.Time
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f.mu.Lock()
+	f.at = append(f.at, time.Now())
+	if q.Get("watch") == "" {
+		f.requests = append(f.requests, "list")
+		body := f.lists[0]
+		if len(f.lists) > 1 {
+			f.lists = f.lists[1:]
+		}
+		f.mu.Unlock()
+		_, _ = w.Write([]byte(body))
+		return
+	}
+	f.requests = append(f.requests, "watch@"+q.Get("resourceVersion"))
+	if len(f.watches) == 0 {
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+	body := f.watches[0]
+	f.watches = f.watches[1:]
+	f.mu.Unlock()
+	var code int
+	if _, err := fmt.Sscanf(body, "status:%d", &code); err == nil {
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(`{"kind":"Status","message":"too old resource version"}`))
+		return
+	}
+	_, _ = w.Write([]byte(body))
+}
+
+func (f *fakeAPI) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func podList(rv string, names ...string) string {
+	items := make([]string, 0, len(names))
+	for _, n := range names {
+		items = append(items, podJSON(n, "1"))
+	}
+	return fmt.Sprintf(`{"metadata":{"resourceVersion":%q},"items":[%s]}`, rv, strings.Join(items, ","))
+}
+
+func podJSON(name, rv string) string {
+	return fmt.Sprintf(`{"metadata":{"namespace":"team-a","name":%q,"uid":"uid-%s","resourceVersion":%q}}`, name, name, rv)
+}
+
+func watchEvents(events ...string) string { return strings.Join(events, "\n") + "\n" }
+
+func podEvent(typ, name, rv string) string {
+	return fmt.Sprintf(`{"type":%q,"object":%s}`, typ, podJSON(name, rv))
+}
+
+// runInformer runs an informer against api until want requests were made.
+func runInformer(t *testing.T, api *fakeAPI, minBackoff time.Duration, want int) (*Informer, []string) {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	inf := NewInformer(NewClient(srv.URL, srv.Client()), "/api/v1/pods", "spec.nodeName=node-a")
+	inf.minBackoff = minBackoff
+
+	var mu sync.Mutex
+	var changes []string
+	inf.OnChange(func(o Object, deleted bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if deleted {
+			changes = append(changes, "-"+o.Metadata.Name)
+		} else {
+			changes = append(changes, "+"+o.Metadata.Name)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		inf.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(api.log()) < want {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("requests = %v, want %d", api.log(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	return inf, changes
+}
+
+func cachedNames(inf *Informer) []string {
+	var out []string
+	for _, o := range inf.List() {
+		out = append(out, o.Metadata.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestInformerResumesFromLastResourceVersion(t *testing.T) {
+	api := &fakeAPI{
+		lists: []string{podList("10", "a")},
+		watches: []string{
+			watchEvents(podEvent("ADDED", "b", "11"), `{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"15"}}}`),
+			watchEvents(podEvent("DELETED", "a", "16")),
+		},
+	}
+	inf, changes := runInformer(t, api, time.Hour, 4)
+
+	if got, want := api.log(), []string{"list", "watch@10", "watch@15", "watch@16"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+	if got := cachedNames(inf); fmt.Sprint(got) != "[b]" {
+		t.Errorf("cache = %v, want [b]", got)
+	}
+	if _, ok := inf.GetByUID("uid-a"); ok {
+		t.Error("deleted pod still found by UID")
+	}
+	if fmt.Sprint(changes) != "[+a +b -a]" {
+		t.Errorf("changes = %v, want [+a +b -a]", changes)
+	}
+}
+
+func TestInformerRelistsOnGone(t *testing.T) {
+	api := &fakeAPI{
+		lists: []string{podList("10", "a", "b"), podList("20", "b"), podList("30", "b", "c")},
+		watches: []string{
+			// Expired while watching, then expired at the start of a watch.
+			watchEvents(`{"type":"ERROR","object":{"kind":"Status","code":410,"message":"too old resource version"}}`),
+			"status:410",
+		},
+	}
+	// An hour of backoff: a 410 must relist at once.
+	inf, changes := runInformer(t, api, time.Hour, 6)
+
+	if got, want := api.log(), []string{"list", "watch@10", "list", "watch@20", "list", "watch@30"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+	if got := cachedNames(inf); fmt.Sprint(got) != "[b c]" {
+		t.Errorf("cache = %v, want [b c]", got)
+	}
+	// A pod missing from the relist is reported deleted.
+	if fmt.Sprint(changes) != "[+a +b -a +b +b +c]" {
+		t.Errorf("changes = %v", changes)
+	}
+}
+
+func TestInformerBacksOffOnBrokenStream(t *testing.T) {
+	for name, stream := range map[string]string{
+		"garbage":    "<html>proxy error</html>\n",
+		"truncated":  `{"type":"ADDED","object":{"metadata":`,
+		"bad object": `{"type":"ADDED","object":[1,2]}` + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			const backoff = 100 * time.Millisecond
+			api := &fakeAPI{lists: []string{podList("10", "a")}, watches: []string{stream}}
+			runInformer(t, api, backoff, 4)
+
+			if got, want := api.log(), []string{"list", "watch@10", "list", "watch@10"}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("requests = %v, want %v", got, want)
+			}
+			api.mu.Lock()
+			wait := api.at[2].Sub(api.at[1])
+			api.mu.Unlock()
+			if wait < backoff {
+				t.Errorf("relisted %v after a broken watch, want at least %v", wait, backoff)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package kube
+
+import "context"
+
+// NamespaceCache keeps Namespace labels for policy selection.
+type NamespaceCache struct {
+	informer *Informer
+}
+
+func NewNamespaceCache(c *Client) *NamespaceCache {
+	return &NamespaceCache{informer: NewInformer(c, "/api/v1/namespaces", "")}
+}
+
+func (n *NamespaceCache) Run(ctx context.Context) { n.informer.Run(ctx) }
+
+func (n *NamespaceCache) HasSynced() bool { return n.informer.HasSynced() }
+
+// NamespaceLabels returns the labels of namespace name, ok=false if unknown.
+func (n *NamespaceCache) NamespaceLabels(name string) (map[string]string, bool) {
+	o, ok := n.informer.Get("", name)
+	if !ok {
+		return nil, false
+	}
+	if o.Metadata.Labels == nil {
+		return map[string]string{}, true
+	}
+	return o.Metadata.Labels, true
+}
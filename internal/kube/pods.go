@@ -0,0 +1,61 @@
+package kube
+
+import (
+	"context"
+	"net/http"
+)
+
+// PodCache is a node-scoped pod metadata cache backed by an Informer, with a
+// direct GET fallback for pods the watch has not delivered yet.
+type PodCache struct {
+	client   *Client
+	informer *Informer
+}
+
+func NewPodCache(c *Client, nodeName string) *PodCache {
+	return &PodCache{
+		client:   c,
+		informer: NewInformer(c, "/api/v1/pods", "spec.nodeName="+nodeName),
+	}
+}
+
+func (p *PodCache) Run(ctx context.Context) { p.informer.Run(ctx) }
+
+func (p *PodCache) HasSynced() bool { return p.informer.HasSynced() }
+
+// PodByUID returns cached pod metadata without hitting the API server.
+func (p *PodCache) PodByUID(uid string) (ObjectMeta, bool) {
+	o, ok := p.informer.GetByUID(uid)
+	return o.Metadata, ok
+}
+
+// Lookup returns pod metadata by UID from the cache, falling back to a GET by
+// namespace/name. The GET result is only accepted if its UID matches, so a
+// recreated pod with the same name is never mistaken for the original.
+func (p *PodCache) Lookup(ctx context.Context, uid, namespace, name string) (ObjectMeta, bool, error) {
+	if uid != "" {
+		if m, ok := p.PodByUID(uid); ok {
+			return m, true, nil
+		}
+	} else if namespace != "" && name != "" {
+		if o, ok := p.informer.Get(namespace, name); ok {
+			return o.Metadata, true, nil
+		}
+	}
+	if namespace == "" || name == "" {
+		return ObjectMeta{}, false, nil
+	}
+
+	var o Object
+	err := p.client.Do(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/pods/"+name, nil, nil, &o)
+	if IsNotFound(err) {
+		return ObjectMeta{}, false, nil
+	}
+	if err != nil {
+		return ObjectMeta{}, false, err
+	}
+	if uid != "" && o.Metadata.UID != uid {
+		return ObjectMeta{}, false, nil
+	}
+	return o.Metadata, true, nil
+}
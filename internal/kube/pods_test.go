@@ -0,0 +1,73 @@
+package kube
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPodCacheLookup(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		gets []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gets = append(gets, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/pods":
+			_, _ = w.Write([]byte(`{"metadata":{"resourceVersion":"10"},"items":[` + podJSON("cached", "1") + `]}`))
+		case "/api/v1/namespaces/team-a/pods/train-0":
+			// Recreated under the same name: a new UID.
+			_, _ = w.Write([]byte(`{"metadata":{"namespace":"team-a","name":"train-0","uid":"uid-new","labels":{"app":"train"}}}`))
+		case "/api/v1/namespaces/team-a/pods/broken":
+			http.Error(w, `{"message":"etcd timeout"}`, http.StatusInternalServerError)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPodCache(NewClient(srv.URL, srv.Client()), "node-a")
+	if _, err := p.informer.list(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name         string
+		uid, ns, pod string
+		wantOK       bool
+		wantUID      string
+		wantErr      bool
+		wantGET      bool
+	}{
+		{"cached by uid", "uid-cached", "team-a", "cached", true, "uid-cached", false, false},
+		{"cached by name", "", "team-a", "cached", true, "uid-cached", false, false},
+		{"get matches uid", "uid-new", "team-a", "train-0", true, "uid-new", false, true},
+		{"get without uid", "", "team-a", "train-0", true, "uid-new", false, true},
+		{"get finds a recreated pod", "uid-old", "team-a", "train-0", false, "", false, true},
+		{"not found", "uid-x", "team-a", "gone", false, "", false, true},
+		{"server error", "uid-x", "team-a", "broken", false, "", true, true},
+		{"uid only", "uid-x", "", "", false, "", false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mu.Lock()
+			gets = nil
+			mu.Unlock()
+
+			m, ok, err := p.Lookup(context.Background(), c.uid, c.ns, c.pod)
+			if ok != c.wantOK || m.UID != c.wantUID || (err != nil) != c.wantErr {
+				t.Errorf("Lookup = %q, %v, %v; want %q, %v, error %v", m.UID, ok, err, c.wantUID, c.wantOK, c.wantErr)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if (len(gets) > 0) != c.wantGET {
+				t.Errorf("API requests = %v, want GET %v", gets, c.wantGET)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package kube
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selector is the equality-based subset of Kubernetes label selectors:
+// comma-separated requirements of the form k=v, k==v, k!=v, k or !k.
+// All requirements must match.
+type Selector struct {
+	reqs []requirement
+	raw  string
+}
+
+type requirement struct {
+	key   string
+	op    string // = != exists !exists
+	value string
+}
+
+func ParseSelector(s string) (Selector, error) {
+	sel := Selector{raw: strings.TrimSpace(s)}
+	if sel.raw == "" {
+		return sel, nil
+	}
+	for _, part := range strings.Split(sel.raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Selector{}, fmt.Errorf("selector %q: empty requirement", s)
+		}
+		var r requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: "!=", value: strings.TrimSpace(kv[1])}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: "=", value: strings.TrimSpace(kv[1])}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: "=", value: strings.TrimSpace(kv[1])}
+		case strings.HasPrefix(part, "!"):
+			r = requirement{key: strings.TrimSpace(part[1:]), op: "!exists"}
+		default:
+			r = requirement{key: part, op: "exists"}
+		}
+		if r.key == "" {
+			return Selector{}, fmt.Errorf("selector %q: missing key in %q", s, part)
+		}
+		// Set-based syntax such as "tier in (a,b)" would otherwise split on
+		// its commas into keys no label ever has, and silently match nothing.
+		if strings.ContainsAny(r.key, " \t()") || strings.ContainsAny(r.value, " \t()") {
+			return Selector{}, fmt.Errorf("selector %q: %q is not an equality requirement (set-based in/notin is not supported)", s, part)
+		}
+		sel.reqs = append(sel.reqs, r)
+	}
+	return sel, nil
+}
+
+// Empty reports whether the selector has no requirements (matches everything).
+func (s Selector) Empty() bool { return len(s.reqs) == 0 }
+
+func (s Selector) String() string { return s.raw }
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.reqs {
+		v, ok := labels[r.key]
+		switch r.op {
+		case "=":
+			if !ok || v != r.value {
+				return false
+			}
+		case "!=":
+			if ok && v == r.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
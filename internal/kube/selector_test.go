@@ -0,0 +1,70 @@
+package kube
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	labels := map[string]string{"gpu-reclaimer/canary": "true", "zone": "a", "empty": ""}
+	cases := []struct {
+		sel     string
+		wantErr bool
+		matches bool
+	}{
+		{"", false, true},
+		{"  ", false, true},
+		{"gpu-reclaimer/canary=true", false, true},
+		{"gpu-reclaimer/canary==true", false, true},
+		{"gpu-reclaimer/canary = true", false, true},
+		{"gpu-reclaimer/canary=false", false, false},
+		{"missing=true", false, false},
+		{"zone!=b", false, true},
+		{"zone!=a", false, false},
+		{"missing!=a", false, true},
+		{"zone", false, true},
+		{"missing", false, false},
+		{"!missing", false, true},
+		{"!zone", false, false},
+		{"empty=", false, true},
+		{"gpu-reclaimer/canary=true, zone=a", false, true},
+		{"gpu-reclaimer/canary=true,zone=b", false, false},
+		{"zone=a,,canary", true, false},
+		{"zone=a,", true, false},
+		{"=true", true, false},
+		{"!=a", true, false},
+		{"!", true, false},
+		{"zone in (a,b)", true, false},
+		{"zone in (a)", true, false},
+		{"zone notin (b)", true, false},
+		{"zone in(a)", true, false},
+		{"zone=a,tier in (online,critical)", true, false},
+		{"!(zone)", true, false},
+		{"zone=a b", true, false},
+	}
+	for _, c := range cases {
+		sel, err := ParseSelector(c.sel)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseSelector(%q) err = %v, want error %v", c.sel, err, c.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := sel.Matches(labels); got != c.matches {
+			t.Errorf("%q matches %v = %v, want %v", c.sel, labels, got, c.matches)
+		}
+	}
+}
+
+func TestSelectorEmpty(t *testing.T) {
+	for s, want := range map[string]bool{"": true, " ": true, "a=b": false, "!a": false} {
+		sel, err := ParseSelector(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel.Empty() != want {
+			t.Errorf("ParseSelector(%q).Empty() = %v, want %v", s, sel.Empty(), want)
+		}
+		if sel.Empty() && !sel.Matches(nil) {
+			t.Errorf("empty selector %q does not match a node without labels", s)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// Metrics holds the agent's Prometheus metrics and renders them in the text
+// exposition format. It is deliberately tiny; we only need counters/gauges.
+type Metrics struct {
+	mu       sync.Mutex
+	families []*family
+
+	reclaimTotal   *family
+	killTotal      *family
+	attribFail     *family
+	idleCandidates *family
+	enforcing      *family
+	fallback       *family
+	podResMismatch *family
+	podResUnused   *family
+
+	gpuUtil     *family
+	gpuMemUtil  *family
+	gpuMemUsed  *family
+	gpuMemTotal *family
+	gpuProcs    *family
+
+	gpuSMActive     *family
+	gpuTensorActive *family
+	gpuHealthy      *family
+}
+
+func New() *Metrics {
+	m := &Metrics{}
+	m.reclaimTotal = m.register("gpu_reclaimer_reclaim_total", "counter", "Reclaim decisions by result, reason and, for failed reclaims, what failed.", "result", "reason", "failure")
+	m.killTotal = m.register("gpu_reclaimer_kill_total", "counter", "Signals sent to GPU processes.", "signal")
+	m.attribFail = m.register("gpu_reclaimer_pid_attribution_fail_total", "counter", "GPU PIDs that could not be attributed to a pod.")
+	m.idleCandidates = m.register("gpu_reclaimer_idle_candidates", "gauge", "Pods currently past the idle threshold.")
+	m.enforcing = m.register("gpu_reclaimer_enforcing", "gauge", "1 when the node gate allows reclaim, 0 when observe-only.")
+	m.podResMismatch = m.register("gpu_reclaimer_podresources_mismatch_pods", "gauge", "Pods running on a GPU the device plugin allocated to other pods.")
+	m.podResUnused = m.register("gpu_reclaimer_podresources_unused_pods", "gauge", "Pods allocated a GPU on this node with no process on it.")
+	m.fallback = m.register("gpu_reclaimer_sampler_fallback", "gauge", "1 while sampler auto uses nvidia-smi because NVML failed.")
+
+	m.gpuUtil = m.register("gpu_reclaimer_gpu_utilization_percent", "gauge", "GPU utilization from the last sample.", "gpu", "uuid")
+	m.gpuMemUtil = m.register("gpu_reclaimer_gpu_memory_utilization_percent", "gauge", "GPU memory controller utilization from the last sample.", "gpu", "uuid")
+	m.gpuMemUsed = m.register("gpu_reclaimer_gpu_memory_used_bytes", "gauge", "GPU memory used from the last sample.", "gpu", "uuid")
+	m.gpuMemTotal = m.register("gpu_reclaimer_gpu_memory_total_bytes", "gauge", "GPU memory total from the last sample.", "gpu", "uuid")
+	m.gpuProcs = m.register("gpu_reclaimer_gpu_compute_processes", "gauge", "Compute processes on the GPU in the last sample.", "gpu", "uuid")
+	m.gpuSMActive = m.register("gpu_reclaimer_gpu_sm_active_percent", "gauge", "SM active percentage from the last sample (DCGM sampler only).", "gpu", "uuid")
+	m.gpuTensorActive = m.register("gpu_reclaimer_gpu_tensor_active_percent", "gauge", "Tensor pipe active percentage from the last sample (DCGM sampler only).", "gpu", "uuid")
+	m.gpuHealthy = m.register("gpu_reclaimer_gpu_healthy", "gauge", "1 when the device could be read in the last sample, 0 when not.", "gpu", "uuid")
+	return m
+}
+
+// IncReclaim counts a decision on an idle candidate: result is success,
+// dry_run or skipped, reason is "idle" or the skip reason.
+func (m *Metrics) IncReclaim(result, reason string) { m.add(m.reclaimTotal, 1, result, reason, "") }
+
+// IncReclaimFailure counts a failed reclaim of an idle pod; failure is
+// "signal" when PIDs survived signalling, else the verify status.
+func (m *Metrics) IncReclaimFailure(failure string) {
+	m.add(m.reclaimTotal, 1, "fail", "idle", failure)
+}
+
+func (m *Metrics) IncKill(signal string)    { m.add(m.killTotal, 1, signal) }
+func (m *Metrics) AddAttributionFail(n int) { m.add(m.attribFail, float64(n)) }
+func (m *Metrics) SetIdleCandidates(n int)  { m.set(m.idleCandidates, float64(n)) }
+
+func (m *Metrics) SetEnforcing(on bool)       { m.set(m.enforcing, boolValue(on)) }
+func (m *Metrics) SetSamplerFallback(on bool) { m.set(m.fallback, boolValue(on)) }
+
+func (m *Metrics) SetPodResourcesMismatches(n int) { m.set(m.podResMismatch, float64(n)) }
+func (m *Metrics) SetPodResourcesUnused(n int)     { m.set(m.podResUnused, float64(n)) }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ObserveSnapshot replaces all per-GPU gauges with values from snap, so GPUs
+// that disappeared stop being exported.
+func (m *Metrics) ObserveSnapshot(snap sampling.Snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range []*family{m.gpuUtil, m.gpuMemUtil, m.gpuMemUsed, m.gpuMemTotal, m.gpuProcs, m.gpuSMActive, m.gpuTensorActive, m.gpuHealthy} {
+		f.reset()
+	}
+	for _, g := range snap.GPUs {
+		// MIG devices are exported per slice, e.g. gpu="0/gi1/ci0".
+		idx := g.ID()
+		m.gpuHealthy.set(boolValue(g.Healthy()), idx, g.UUID)
+		if !g.Healthy() {
+			// Its process list is unknown, not empty.
+			continue
+		}
+		// Unknown values are left out rather than exported as 0.
+		if !g.UtilGPUUnknown {
+			m.gpuUtil.set(float64(g.UtilGPU), idx, g.UUID)
+		}
+		if !g.UtilMemUnknown {
+			m.gpuMemUtil.set(float64(g.UtilMem), idx, g.UUID)
+		}
+		if !g.MemUsedUnknown {
+			m.gpuMemUsed.set(float64(g.MemUsedBytes), idx, g.UUID)
+		}
+		if !g.MemTotalUnknown {
+			m.gpuMemTotal.set(float64(g.MemTotalBytes), idx, g.UUID)
+		}
+		m.gpuProcs.set(float64(len(g.ComputeProcs)), idx, g.UUID)
+		if g.HasActivity {
+			m.gpuSMActive.set(g.SMActivePct, idx, g.UUID)
+			m.gpuTensorActive.set(g.TensorActivePct, idx, g.UUID)
+		}
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WriteText(w)
+}
+
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, f := range m.families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (m *Metrics) register(name, kind, help string, labels ...string) *family {
+	f := &family{name: name, kind: kind, help: help, labels: labels, series: map[string]*series{}}
+	if len(labels) == 0 {
+		// Unlabelled metrics are always exported, starting at zero.
+		f.get()
+	}
+	m.families = append(m.families, f)
+	return f
+}
+
+func (m *Metrics) add(f *family, v float64, labelValues ...string) {
+	if v < 0 && f.kind == "counter" {
+		// Counters only go up; Prometheus would read a decrease as a reset.
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f.get(labelValues...).value += v
+}
+
+func (m *Metrics) set(f *family, v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f.set(v, labelValues...)
+}
+
+// ---- exposition ----
+
+type family struct {
+	name   string
+	kind   string
+	help   string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+func (f *family) get(labelValues ...string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s := f.series[key]
+	if s == nil {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) set(v float64, labelValues ...string) { f.get(labelValues...).value = v }
+
+func (f *family) reset() { f.series = map[string]*series{} }
+
+func (f *family) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		w.WriteString(f.name)
+		if len(f.labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range f.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				v := ""
+				if i < len(s.labelValues) {
+					v = s.labelValues[i]
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l, labelEscaper.Replace(v))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+		w.WriteByte('\n')
+	}
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
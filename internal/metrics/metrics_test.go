@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+func exposition(t *testing.T, m *Metrics) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := m.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWriteTextGolden(t *testing.T) {
+	m := New()
+	m.IncReclaim("skipped", "protected_namespace")
+	m.IncReclaim("skipped", "reclaim_in_progress")
+	m.IncReclaim("success", "idle")
+	m.IncReclaim("success", "idle")
+	m.IncReclaimFailure("signal")
+	m.IncReclaimFailure("still_present")
+	m.IncKill("TERM")
+	m.AddAttributionFail(3)
+	m.SetEnforcing(true)
+	m.ObserveSnapshot(sampling.Snapshot{GPUs: []sampling.GPUSnapshot{
+		{Index: 0, UUID: "GPU-0", UtilGPU: 7, UtilMem: 2, MemUsedBytes: 1 << 30, MemTotalBytes: 80 << 30,
+			ComputeProcs: []sampling.GPUProcess{{PID: 42}}},
+		// Label values with every character the format escapes.
+		{Index: 1, UUID: "GPU-\"odd\"\\path\nline", UtilGPUUnknown: true, UtilMem: 0, MemTotalBytes: 16 << 30},
+		{Index: 2, UUID: "GPU-2", Error: "GPU is lost"},
+	}})
+
+	got := exposition(t, m)
+	path := filepath.Join("testdata", "metrics.golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("exposition differs from %s (run with -update to accept):\n%s", path, got)
+	}
+}
+
+// Every family is introduced by HELP then TYPE, and its samples follow
+// before the next family starts.
+func TestWriteTextHelpTypeOrder(t *testing.T) {
+	m := New()
+	m.IncReclaim("dry_run", "idle")
+	m.IncKill("KILL")
+	m.ObserveSnapshot(sampling.Snapshot{GPUs: []sampling.GPUSnapshot{{Index: 0, UUID: "GPU-0"}}})
+
+	var family string
+	seen := map[string]bool{}
+	lines := strings.Split(strings.TrimSuffix(exposition(t, m), "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			family = strings.Fields(line)[2]
+			if seen[family] {
+				t.Errorf("family %s described twice", family)
+			}
+			seen[family] = true
+			if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "# TYPE "+family+" ") {
+				t.Errorf("HELP for %s not followed by its TYPE", family)
+			}
+		case strings.HasPrefix(line, "# TYPE "):
+			if name := strings.Fields(line)[2]; name != family {
+				t.Errorf("TYPE for %s without its HELP first", name)
+			}
+		default:
+			name := line[:strings.IndexAny(line, "{ ")]
+			if name != family {
+				t.Errorf("sample %q under family %s", line, family)
+			}
+		}
+	}
+	if len(seen) != len(m.families) {
+		t.Errorf("%d families written, %d registered", len(seen), len(m.families))
+	}
+}
+
+func TestCountersOnlyIncrease(t *testing.T) {
+	m := New()
+	m.AddAttributionFail(2)
+	m.IncReclaim("success", "idle")
+	// Per-GPU gauges are replaced each snapshot; counters are not.
+	m.ObserveSnapshot(sampling.Snapshot{})
+	m.AddAttributionFail(-5)
+	m.AddAttributionFail(0)
+	m.AddAttributionFail(1)
+
+	out := exposition(t, m)
+	for _, want := range []string{
+		"gpu_reclaimer_pid_attribution_fail_total 3\n",
+		`gpu_reclaimer_reclaim_total{result="success",reason="idle",failure=""} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition lacks %q:\n%s", want, out)
+		}
+	}
+}
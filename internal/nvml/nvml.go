@@ -3,6 +3,7 @@ package nvmlwrap
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
@@ -11,12 +12,44 @@ import (
 
 // Sampler implements sampling via NVML (go-nvml cgo bindings).
 
+// valueNotAvailable is NVML_VALUE_NOT_AVAILABLE as reported in
+// usedGpuMemory, e.g. without permission to query other containers.
+const valueNotAvailable = ^uint64(0)
+
+// DefaultReinitInterval limits how often NVML is re-initialized while a
+// device keeps failing; a GPU that fell off the bus stays lost until reset.
+const DefaultReinitInterval = time.Minute
+
+// DefaultProcUtilWindow matches the default sample interval.
+const DefaultProcUtilWindow = time.Minute
+
 type Client struct {
 	initialized bool
+
+	// ReinitInterval is the minimum time between re-initializations after
+	// device errors. Handles do not survive a driver reload, so NVML is
+	// shut down and initialized again to pick up the new driver.
+	ReinitInterval time.Duration
+	needReinit     bool
+	lastInit       time.Time
+
+	// ProcUtilWindow is the minimum period per-process utilization is read
+	// over, normally the sample interval. Without it a Sample taken right
+	// after another (candidate validation) would see an almost empty window
+	// and read every process as 0%.
+	ProcUtilWindow time.Duration
+
+	// Newest per-process utilization sample seen per GPU UUID, so each
+	// Sample covers at least the period since the previous one.
+	procUtilSeen map[string]uint64
 }
 
 func New() *Client {
-	return &Client{}
+	return &Client{
+		ReinitInterval: DefaultReinitInterval,
+		ProcUtilWindow: DefaultProcUtilWindow,
+		procUtilSeen:   map[string]uint64{},
+	}
 }
 
 func (c *Client) Init() error {
@@ -28,6 +61,8 @@ func (c *Client) Init() error {
 		return fmt.Errorf("nvml init failed: %s", nvml.ErrorString(ret))
 	}
 	c.initialized = true
+	c.needReinit = false
+	c.lastInit = time.Now()
 	return nil
 }
 
@@ -46,14 +81,23 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Sample reads every GPU. A device that cannot be read is reported as an
+// unhealthy entry (see sampling.UnhealthyGPU) while the others are sampled
+// normally; only failures that affect all devices fail the sample.
 func (c *Client) Sample(ctx context.Context) (sampling.Snapshot, error) {
 	_ = ctx
+	if c.needReinit && time.Since(c.lastInit) >= c.ReinitInterval {
+		c.Shutdown()
+	}
 	if err := c.Init(); err != nil {
 		return sampling.Snapshot{}, err
 	}
 
 	count, ret := nvml.DeviceGetCount()
 	if ret != nvml.SUCCESS {
+		// Typically the driver was unloaded or reloaded; start over on the
+		// next sample.
+		c.Shutdown()
 		return sampling.Snapshot{}, fmt.Errorf("nvml device get count failed: %s", nvml.ErrorString(ret))
 	}
 
@@ -61,29 +105,148 @@ func (c *Client) Sample(ctx context.Context) (sampling.Snapshot, error) {
 	for i := 0; i < count; i++ {
 		dev, ret := nvml.DeviceGetHandleByIndex(i)
 		if ret != nvml.SUCCESS {
-			return sampling.Snapshot{}, fmt.Errorf("nvml get handle index=%d failed: %s", i, nvml.ErrorString(ret))
+			snap.GPUs = append(snap.GPUs, sampling.UnhealthyGPU(i, "", "get handle: "+nvml.ErrorString(ret)))
+			continue
 		}
 
 		uuid, _ := dev.GetUUID()
-		util, _ := dev.GetUtilizationRates()
-		memInfo, _ := dev.GetMemoryInfo()
+		if mode, _, ret := dev.GetMigMode(); ret == nvml.SUCCESS && mode == nvml.DEVICE_MIG_ENABLE {
+			migs, err := c.sampleMIG(dev, i, uuid)
+			if err != nil {
+				snap.GPUs = append(snap.GPUs, sampling.UnhealthyGPU(i, uuid, err.Error()))
+				continue
+			}
+			snap.GPUs = append(snap.GPUs, migs...)
+			continue
+		}
+		snap.GPUs = append(snap.GPUs, c.sampleDevice(dev, i, uuid))
+	}
+
+	if len(snap.Unhealthy()) > 0 {
+		c.needReinit = true
+	}
+	return snap, nil
+}
+
+// sampleDevice reads one GPU or MIG device. index is the (parent) GPU index.
+// Without the process list nothing on the device can be attributed or
+// judged, so failing to read it marks the device unhealthy.
+func (c *Client) sampleDevice(dev nvml.Device, index int, uuid string) sampling.GPUSnapshot {
+	util, utilRet := dev.GetUtilizationRates()
+	memInfo, memRet := dev.GetMemoryInfo()
 
-		procs, _ := dev.GetComputeRunningProcesses()
-		procList := make([]sampling.GPUProcess, 0, len(procs))
-		for _, p := range procs {
-			procList = append(procList, sampling.GPUProcess{PID: int(p.Pid), UsedBytes: p.UsedGpuMemory})
+	procs, ret := dev.GetComputeRunningProcesses()
+	if ret != nvml.SUCCESS {
+		return sampling.UnhealthyGPU(index, uuid, "get compute processes: "+nvml.ErrorString(ret))
+	}
+	procUtil, procUtilOK := c.processUtilization(dev, uuid)
+	procList := make([]sampling.GPUProcess, 0, len(procs))
+	for _, p := range procs {
+		gp := sampling.GPUProcess{PID: int(p.Pid), UsedBytes: p.UsedGpuMemory}
+		if p.UsedGpuMemory == valueNotAvailable {
+			gp.UsedBytes, gp.UsedBytesUnknown = 0, true
 		}
+		if procUtilOK {
+			u := procUtil[p.Pid]
+			gp.HasUtil, gp.SMUtil, gp.MemUtil = true, u.SmUtil, u.MemUtil
+			gp.EncUtil, gp.DecUtil = u.EncUtil, u.DecUtil
+		}
+		procList = append(procList, gp)
+	}
 
-		snap.GPUs = append(snap.GPUs, sampling.GPUSnapshot{
-			Index:         i,
-			UUID:          uuid,
-			UtilGPU:       util.Gpu,
-			UtilMem:       util.Memory,
-			MemUsedBytes:  memInfo.Used,
-			MemTotalBytes: memInfo.Total,
-			ComputeProcs:  procList,
-		})
+	return sampling.GPUSnapshot{
+		Index:         index,
+		UUID:          uuid,
+		UtilGPU:       util.Gpu,
+		UtilMem:       util.Memory,
+		MemUsedBytes:  memInfo.Used,
+		MemTotalBytes: memInfo.Total,
+		ComputeProcs:  procList,
+
+		UtilGPUUnknown:  utilRet != nvml.SUCCESS,
+		UtilMemUnknown:  utilRet != nvml.SUCCESS,
+		MemUsedUnknown:  memRet != nvml.SUCCESS,
+		MemTotalUnknown: memRet != nvml.SUCCESS,
 	}
+}
 
-	return snap, nil
+// sampleMIG reads every MIG device of a MIG-enabled GPU as its own entry, so
+// processes are attributed to their slice and judged on it. NVML reports
+// neither utilization nor per-process utilization for MIG devices, so such
+// entries stay unknown (never idle) with this sampler; DCGM provides
+// per-instance activity.
+func (c *Client) sampleMIG(parent nvml.Device, index int, parentUUID string) ([]sampling.GPUSnapshot, error) {
+	n, ret := parent.GetMaxMigDeviceCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("nvml get max mig device count index=%d failed: %s", index, nvml.ErrorString(ret))
+	}
+	var out []sampling.GPUSnapshot
+	for mi := 0; mi < n; mi++ {
+		mig, ret := parent.GetMigDeviceHandleByIndex(mi)
+		if ret == nvml.ERROR_NOT_FOUND {
+			// Unpopulated slot.
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("nvml get mig device index=%d/%d failed: %s", index, mi, nvml.ErrorString(ret))
+		}
+		uuid, _ := mig.GetUUID()
+		gi, ret := mig.GetGpuInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("nvml get gpu instance id %s failed: %s", uuid, nvml.ErrorString(ret))
+		}
+		ci, ret := mig.GetComputeInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("nvml get compute instance id %s failed: %s", uuid, nvml.ErrorString(ret))
+		}
+		g := c.sampleDevice(mig, index, uuid)
+		g.MIG = &sampling.MIGDevice{ParentUUID: parentUUID, GPUInstanceID: gi, ComputeInstanceID: ci}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// processUtilization returns the peak SM/memory utilization per PID since
+// the previous call for this GPU, or over the last ProcUtilWindow if that is
+// longer. A process without samples in that window did not run and counts
+// as 0%. ok is false when the driver cannot report
+// per-process utilization (e.g. vGPU or old drivers); callers then fall back
+// to card-level utilization.
+func (c *Client) processUtilization(dev nvml.Device, uuid string) (map[uint32]nvml.ProcessUtilizationSample, bool) {
+	// Timestamps are CPU time in microseconds.
+	since := c.procUtilSeen[uuid]
+	if lookBack := time.Now().Add(-c.ProcUtilWindow).UnixMicro(); lookBack > 0 && uint64(lookBack) < since {
+		since = uint64(lookBack)
+	}
+	samples, ret := dev.GetProcessUtilization(since)
+	switch ret {
+	case nvml.SUCCESS:
+	case nvml.ERROR_NOT_FOUND:
+		// No samples in the window: nothing ran.
+		return map[uint32]nvml.ProcessUtilizationSample{}, true
+	default:
+		return nil, false
+	}
+	peak := map[uint32]nvml.ProcessUtilizationSample{}
+	for _, s := range samples {
+		p := peak[s.Pid]
+		p.Pid = s.Pid
+		if s.SmUtil > p.SmUtil {
+			p.SmUtil = s.SmUtil
+		}
+		if s.MemUtil > p.MemUtil {
+			p.MemUtil = s.MemUtil
+		}
+		if s.EncUtil > p.EncUtil {
+			p.EncUtil = s.EncUtil
+		}
+		if s.DecUtil > p.DecUtil {
+			p.DecUtil = s.DecUtil
+		}
+		peak[s.Pid] = p
+		if s.TimeStamp > c.procUtilSeen[uuid] {
+			c.procUtilSeen[uuid] = s.TimeStamp
+		}
+	}
+	return peak, true
 }
@@ -0,0 +1,116 @@
+// Package podresources reads device allocations from the kubelet
+// PodResources API, i.e. which container the device plugin handed each GPU
+// to. It is independent of what actually runs on the GPU, which makes it a
+// cross-check for cgroup-based PID attribution.
+package podresources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// DefaultSocket is where kubelet serves the PodResources API.
+const DefaultSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+// Allocation is one device assigned to a container.
+type Allocation struct {
+	Namespace string
+	Pod       string
+	Container string
+	Resource  string
+	DeviceID  string
+}
+
+// Client lists allocations over the kubelet socket. The connection is made
+// on first use.
+type Client struct {
+	socket string
+
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+	api  podresourcesapi.PodResourcesListerClient
+}
+
+// New returns a client for socket ("unix:///path" or a plain path).
+func New(socket string) *Client {
+	return &Client{socket: strings.TrimSpace(socket)}
+}
+
+func (c *Client) client() (podresourcesapi.PodResourcesListerClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.api != nil {
+		return c.api, nil
+	}
+	target := c.socket
+	if !strings.Contains(target, "://") {
+		target = "unix://" + target
+	}
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial pod resources %s: %w", target, err)
+	}
+	c.conn, c.api = conn, podresourcesapi.NewPodResourcesListerClient(conn)
+	return c.api, nil
+}
+
+// List returns every device allocation on the node.
+func (c *Client) List(ctx context.Context) ([]Allocation, error) {
+	api, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("pod resources list: %w", err)
+	}
+	var out []Allocation
+	for _, pod := range resp.GetPodResources() {
+		for _, ctr := range pod.GetContainers() {
+			for _, dev := range ctr.GetDevices() {
+				for _, id := range dev.GetDeviceIds() {
+					out = append(out, Allocation{
+						Namespace: pod.GetNamespace(),
+						Pod:       pod.GetName(),
+						Container: ctr.GetName(),
+						Resource:  dev.GetResourceName(),
+						DeviceID:  id,
+					})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.api = nil, nil
+	return err
+}
+
+// ByDevice indexes allocations by device ID. Replica suffixes such as
+// "GPU-<uuid>::2" from the NVIDIA device plugin's time-slicing are dropped,
+// so the keys match the UUIDs NVML and nvidia-smi report.
+func ByDevice(allocs []Allocation) map[string][]Allocation {
+	out := map[string][]Allocation{}
+	for _, a := range allocs {
+		id := a.DeviceID
+		if i := strings.Index(id, "::"); i >= 0 {
+			id = id[:i]
+		}
+		out[id] = append(out[id], a)
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package podresources
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// fakeKubelet serves a fixed List response.
+type fakeKubelet struct {
+	podresourcesapi.UnimplementedPodResourcesListerServer
+	pods []*podresourcesapi.PodResources
+}
+
+func (f *fakeKubelet) List(context.Context, *podresourcesapi.ListPodResourcesRequest) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{PodResources: f.pods}, nil
+}
+
+// serveKubelet serves f on a unix socket and returns its path.
+func serveKubelet(t *testing.T, f podresourcesapi.PodResourcesListerServer) string {
+	t.Helper()
+	// t.TempDir can exceed the 108-byte sun_path limit.
+	dir, err := os.MkdirTemp("", "podres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "kubelet.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	podresourcesapi.RegisterPodResourcesListerServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return sock
+}
+
+func TestList(t *testing.T) {
+	sock := serveKubelet(t, &fakeKubelet{pods: []*podresourcesapi.PodResources{
+		{
+			Namespace: "team-a", Name: "train-0",
+			Containers: []*podresourcesapi.ContainerResources{
+				{Name: "trainer", Devices: []*podresourcesapi.ContainerDevices{
+					{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-0", "GPU-1"}},
+				}},
+				{Name: "sidecar"},
+			},
+		},
+		{
+			Namespace: "team-b", Name: "infer-0",
+			Containers: []*podresourcesapi.ContainerResources{
+				{Name: "server", Devices: []*podresourcesapi.ContainerDevices{
+					{ResourceName: "rdma/hca", DeviceIds: []string{"mlx5_0"}},
+				}},
+			},
+		},
+	}})
+	for _, target := range []string{sock, "unix://" + sock} {
+		c := New(target)
+		got, err := c.List(context.Background())
+		c.Close()
+		if err != nil {
+			t.Fatalf("List(%s): %v", target, err)
+		}
+		want := []Allocation{
+			{Namespace: "team-a", Pod: "train-0", Container: "trainer", Resource: "nvidia.com/gpu", DeviceID: "GPU-0"},
+			{Namespace: "team-a", Pod: "train-0", Container: "trainer", Resource: "nvidia.com/gpu", DeviceID: "GPU-1"},
+			{Namespace: "team-b", Pod: "infer-0", Container: "server", Resource: "rdma/hca", DeviceID: "mlx5_0"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("List(%s) =\n%+v\nwant\n%+v", target, got, want)
+		}
+	}
+}
+
+func TestByDevice(t *testing.T) {
+	a := Allocation{Namespace: "team-a", Pod: "train-0", DeviceID: "GPU-0::1"}
+	b := Allocation{Namespace: "team-b", Pod: "infer-0", DeviceID: "GPU-0::2"}
+	c := Allocation{Namespace: "team-b", Pod: "infer-0", DeviceID: "GPU-1"}
+	got := ByDevice([]Allocation{a, b, c})
+	want := map[string][]Allocation{"GPU-0": {a, b}, "GPU-1": {c}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ByDevice =\n%+v\nwant\n%+v", got, want)
+	}
+}
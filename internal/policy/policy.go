@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/idle"
+	"gpu-reclaimer-agent/internal/kube"
+)
+
+// Resolver maps a namespace to the idle policy tier it falls under.
+type Resolver struct {
+	def   idle.Policy
+	tiers []tier
+}
+
+type tier struct {
+	policy     idle.Policy
+	namespaces []string
+	nsSel      kube.Selector
+}
+
+// NewResolver compiles the configured tiers. Thresholds a tier leaves unset
+// are inherited from cfg; if a tier only sets IdleMinutes, its consecutive
+// sample count is derived from the sample interval so the two stay aligned.
+func NewResolver(cfg config.Config) (*Resolver, error) {
+	r := &Resolver{
+		def: idle.Policy{
+			Name:                   "default",
+			IdleMinutes:            cfg.IdleMinutes,
+			ConsecutiveIdleSamples: cfg.ConsecutiveIdleSamples,
+			UtilThresholdPct:       cfg.GPUUtilThresholdPct,
+			ReclaimTarget:          cfg.ReclaimTarget,
+		},
+	}
+	for i, p := range cfg.Policies {
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			name = fmt.Sprintf("tier-%d", i)
+		}
+		for _, pat := range p.Namespaces {
+			if _, err := path.Match(pat, ""); err != nil {
+				return nil, fmt.Errorf("policy %q: bad namespace pattern %q: %w", name, pat, err)
+			}
+		}
+		sel, err := kube.ParseSelector(p.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+
+		ip := idle.Policy{
+			Name:                   name,
+			IdleMinutes:            p.IdleMinutes,
+			ConsecutiveIdleSamples: p.ConsecutiveIdleSamples,
+			UtilThresholdPct:       p.GPUUtilThresholdPct,
+			ReclaimTarget:          p.ReclaimTarget,
+		}
+		if ip.ReclaimTarget == "" {
+			ip.ReclaimTarget = r.def.ReclaimTarget
+		}
+		if ip.IdleMinutes <= 0 {
+			ip.IdleMinutes = r.def.IdleMinutes
+		}
+		if ip.UtilThresholdPct <= 0 {
+			ip.UtilThresholdPct = r.def.UtilThresholdPct
+		}
+		if ip.ConsecutiveIdleSamples <= 0 {
+			ip.ConsecutiveIdleSamples = samplesFor(ip.IdleMinutes, cfg.SampleInterval, r.def.ConsecutiveIdleSamples)
+		}
+		r.tiers = append(r.tiers, tier{policy: ip, namespaces: p.Namespaces, nsSel: sel})
+	}
+	return r, nil
+}
+
+// NeedsNamespaceLabels reports whether any tier selects on namespace labels.
+func (r *Resolver) NeedsNamespaceLabels() bool {
+	for _, t := range r.tiers {
+		if !t.nsSel.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the first tier matching namespace. nsLabels is nil when the
+// Namespace object is unknown (cache not synced, no RBAC); if a tier with a
+// label selector could then match, ok is false: falling through to a
+// stricter tier could reclaim a pod its own tier would keep. For the same
+// reason an empty namespace (attribution without pod metadata) only gets the
+// default policy with ok set when there are no tiers it could have matched.
+func (r *Resolver) Resolve(namespace string, nsLabels map[string]string) (p idle.Policy, ok bool) {
+	if namespace == "" {
+		return r.def, len(r.tiers) == 0
+	}
+	for _, t := range r.tiers {
+		if len(t.namespaces) > 0 && !matchAny(t.namespaces, namespace) {
+			continue
+		}
+		if !t.nsSel.Empty() {
+			if nsLabels == nil {
+				return r.def, false
+			}
+			if !t.nsSel.Matches(nsLabels) {
+				continue
+			}
+		}
+		return t.policy, true
+	}
+	return r.def, true
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func samplesFor(idleMinutes int, interval time.Duration, fallback int) int {
+	if interval <= 0 {
+		return fallback
+	}
+	n := int((time.Duration(idleMinutes) * time.Minute) / interval)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
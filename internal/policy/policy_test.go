@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/config"
+)
+
+func TestResolveNamespaceLabels(t *testing.T) {
+	cfg := config.Config{
+		IdleMinutes:            30,
+		ConsecutiveIdleSamples: 30,
+		GPUUtilThresholdPct:    1,
+		SampleInterval:         time.Minute,
+		Policies: []config.Policy{
+			{Name: "dev", Namespaces: []string{"dev-*"}, IdleMinutes: 120},
+			{Name: "research", NamespaceSelector: "team=research", IdleMinutes: 120},
+		},
+	}
+	r, err := NewResolver(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.NeedsNamespaceLabels() {
+		t.Error("NeedsNamespaceLabels = false")
+	}
+
+	cases := []struct {
+		name     string
+		ns       string
+		labels   map[string]string
+		wantTier string
+		wantOK   bool
+	}{
+		{"glob tier needs no labels", "dev-a", nil, "dev", true},
+		{"selector matches", "ml", map[string]string{"team": "research"}, "research", true},
+		{"selector does not match", "ml", map[string]string{"team": "infra"}, "default", true},
+		{"labels unknown", "ml", nil, "default", false},
+		{"no namespace", "", nil, "default", false},
+		{"no namespace with labels", "", map[string]string{"team": "infra"}, "default", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, ok := r.Resolve(c.ns, c.labels)
+			if p.Name != c.wantTier || ok != c.wantOK {
+				t.Errorf("Resolve(%q) = %s/%v, want %s/%v", c.ns, p.Name, ok, c.wantTier, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutTiers(t *testing.T) {
+	r, err := NewResolver(config.Config{IdleMinutes: 30, ConsecutiveIdleSamples: 30, GPUUtilThresholdPct: 1, SampleInterval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"", "ml"} {
+		if p, ok := r.Resolve(ns, nil); p.Name != "default" || !ok {
+			t.Errorf("Resolve(%q) = %s/%v, want default/true", ns, p.Name, ok)
+		}
+	}
+}
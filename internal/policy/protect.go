@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gpu-reclaimer-agent/internal/config"
+	"gpu-reclaimer-agent/internal/kube"
+)
+
+// Protection is the never-reclaim list for critical workloads (PRD FR-11):
+// namespace name globs and pod label selectors.
+type Protection struct {
+	namespaces   []string
+	podSelectors []kube.Selector
+}
+
+func NewProtection(cfg config.Config) (*Protection, error) {
+	p := &Protection{}
+	for _, pat := range cfg.ProtectedNamespaces {
+		pat = strings.TrimSpace(pat)
+		if pat == "" {
+			continue
+		}
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("protected namespace pattern %q: %w", pat, err)
+		}
+		p.namespaces = append(p.namespaces, pat)
+	}
+	for _, raw := range cfg.ProtectedPodSelectors {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		sel, err := kube.ParseSelector(raw)
+		if err != nil {
+			return nil, fmt.Errorf("protected pod selector: %w", err)
+		}
+		p.podSelectors = append(p.podSelectors, sel)
+	}
+	return p, nil
+}
+
+func (p *Protection) HasNamespaces() bool   { return len(p.namespaces) > 0 }
+func (p *Protection) HasPodSelectors() bool { return len(p.podSelectors) > 0 }
+
+// NamespaceRule returns the pattern protecting namespace, if any.
+func (p *Protection) NamespaceRule(namespace string) (string, bool) {
+	for _, pat := range p.namespaces {
+		if ok, _ := path.Match(pat, namespace); ok {
+			return pat, true
+		}
+	}
+	return "", false
+}
+
+// PodLabelRule returns the selector protecting a pod with labels, if any.
+func (p *Protection) PodLabelRule(labels map[string]string) (string, bool) {
+	for _, sel := range p.podSelectors {
+		if sel.Matches(labels) {
+			return sel.String(), true
+		}
+	}
+	return "", false
+}
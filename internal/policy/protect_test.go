@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/config"
+)
+
+func TestNamespaceRule(t *testing.T) {
+	p, err := NewProtection(config.Config{ProtectedNamespaces: []string{"kube-system", " ", "infra-*", "prod-?"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.HasNamespaces() || p.HasPodSelectors() {
+		t.Errorf("HasNamespaces/HasPodSelectors = %v/%v, want true/false", p.HasNamespaces(), p.HasPodSelectors())
+	}
+	cases := []struct {
+		ns       string
+		wantRule string
+		wantOK   bool
+	}{
+		{"kube-system", "kube-system", true},
+		{"kube-system-2", "", false},
+		{"infra-monitoring", "infra-*", true},
+		{"infra-", "infra-*", true},
+		{"team-infra", "", false},
+		{"prod-a", "prod-?", true},
+		{"prod-ab", "", false},
+		{"team-a", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		rule, ok := p.NamespaceRule(c.ns)
+		if rule != c.wantRule || ok != c.wantOK {
+			t.Errorf("NamespaceRule(%q) = %q, %v; want %q, %v", c.ns, rule, ok, c.wantRule, c.wantOK)
+		}
+	}
+}
+
+func TestPodLabelRule(t *testing.T) {
+	p, err := NewProtection(config.Config{ProtectedPodSelectors: []string{"tier=online", "app=db,env!=dev", "gpu-reclaimer/protected"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HasNamespaces() || !p.HasPodSelectors() {
+		t.Errorf("HasNamespaces/HasPodSelectors = %v/%v, want false/true", p.HasNamespaces(), p.HasPodSelectors())
+	}
+	cases := []struct {
+		name     string
+		labels   map[string]string
+		wantRule string
+		wantOK   bool
+	}{
+		{"no labels", nil, "", false},
+		{"equality", map[string]string{"tier": "online"}, "tier=online", true},
+		{"other value", map[string]string{"tier": "batch"}, "", false},
+		{"all requirements", map[string]string{"app": "db", "env": "prod"}, "app=db,env!=dev", true},
+		{"one requirement fails", map[string]string{"app": "db", "env": "dev"}, "", false},
+		{"exists", map[string]string{"gpu-reclaimer/protected": ""}, "gpu-reclaimer/protected", true},
+		{"first match wins", map[string]string{"tier": "online", "app": "db"}, "tier=online", true},
+	}
+	for _, c := range cases {
+		rule, ok := p.PodLabelRule(c.labels)
+		if rule != c.wantRule || ok != c.wantOK {
+			t.Errorf("%s: PodLabelRule(%v) = %q, %v; want %q, %v", c.name, c.labels, rule, ok, c.wantRule, c.wantOK)
+		}
+	}
+}
+
+func TestNewProtectionRejectsBadRules(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{"bad glob", config.Config{ProtectedNamespaces: []string{"kube-["}}, "kube-["},
+		{"set-based selector", config.Config{ProtectedPodSelectors: []string{"tier in (online,critical)"}}, "not supported"},
+		{"empty requirement", config.Config{ProtectedPodSelectors: []string{"tier=online,"}}, "empty requirement"},
+	}
+	for _, c := range cases {
+		_, err := NewProtection(c.cfg)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want one mentioning %q", c.name, err, c.want)
+		}
+	}
+}
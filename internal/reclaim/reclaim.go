@@ -0,0 +1,239 @@
+package reclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gpu-reclaimer-agent/internal/clock"
+)
+
+// Executor reclaims GPU processes: SIGTERM, wait for the grace period, then
+// SIGKILL whatever is still alive (PRD FR-7/FR-8). A full TERM->KILL round is
+// retried up to MaxRetry times for processes that survive it.
+type Executor struct {
+	Grace        time.Duration
+	KillWait     time.Duration
+	MaxRetry     int
+	PollInterval time.Duration
+
+	// Signal sends sig to pid (syscall.Kill by default); signal 0 probes
+	// whether pid exists.
+	Signal func(pid int, sig syscall.Signal) error
+	// ProcRoot is the procfs read to tell zombies apart and to catch
+	// recycled PIDs by their start time (default "/proc").
+	ProcRoot string
+	// Clock timestamps signals (default wall time). Grace periods are
+	// always waited out in wall time.
+	Clock clock.Clock
+}
+
+func NewExecutor(grace time.Duration, maxRetry int) *Executor {
+	if maxRetry < 0 {
+		maxRetry = 0
+	}
+	return &Executor{
+		Grace:        grace,
+		KillWait:     5 * time.Second,
+		MaxRetry:     maxRetry,
+		PollInterval: 250 * time.Millisecond,
+		Signal:       syscall.Kill,
+		ProcRoot:     "/proc",
+		Clock:        clock.Real{},
+	}
+}
+
+type SignalEvent struct {
+	PID     int
+	Signal  string // TERM|KILL
+	Attempt int
+	At      time.Time
+	Error   string
+}
+
+type Result struct {
+	PIDs      []int
+	Attempts  int
+	Signals   []SignalEvent
+	Remaining []int
+	// NotOwned are PIDs dropped before a signal round because they were
+	// recycled: the process exited and the PID now belongs to another one.
+	NotOwned []int
+	Err      error
+}
+
+func (r Result) Success() bool { return r.Err == nil && len(r.Remaining) == 0 }
+
+// Reclaim signals pids until they are gone or retries are exhausted.
+// PIDs that are already gone are skipped without being signalled.
+//
+// A grace period and retries can span many seconds, long enough for a
+// process to exit and its PID to be reused. Before every signal round each
+// PID is checked against the start time it had when Reclaim began and, if
+// owned is set, against owned (e.g. that it still attributes to the pod
+// being reclaimed); a PID failing either is dropped into Result.NotOwned
+// and never signalled again.
+func (e *Executor) Reclaim(ctx context.Context, pids []int, owned func(pid int) bool) Result {
+	res := Result{PIDs: append([]int(nil), pids...)}
+
+	starts := map[int]string{}
+	for _, pid := range pids {
+		starts[pid] = e.startTime(pid)
+	}
+	confirm := func(pids []int) []int {
+		out := pids[:0:0]
+		for _, pid := range pids {
+			if !e.alive(pid) {
+				// Exited since the last check; nothing to signal or doubt.
+				continue
+			}
+			st := e.startTime(pid)
+			reused := st != "" && starts[pid] != "" && st != starts[pid]
+			if reused || owned != nil && !owned(pid) {
+				res.NotOwned = append(res.NotOwned, pid)
+				continue
+			}
+			out = append(out, pid)
+		}
+		return out
+	}
+
+	remaining := e.filterAlive(pids)
+	for attempt := 0; attempt <= e.MaxRetry && len(remaining) > 0; attempt++ {
+		if remaining = confirm(remaining); len(remaining) == 0 {
+			break
+		}
+		res.Attempts++
+
+		e.sendAll(remaining, syscall.SIGTERM, attempt, &res)
+		remaining = e.waitGone(ctx, remaining, e.Grace)
+		if len(remaining) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		if remaining = confirm(remaining); len(remaining) == 0 {
+			break
+		}
+		e.sendAll(remaining, syscall.SIGKILL, attempt, &res)
+		remaining = e.waitGone(ctx, remaining, e.KillWait)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	res.Remaining = remaining
+	if ctx.Err() != nil && len(remaining) > 0 {
+		res.Err = ctx.Err()
+	} else if len(remaining) > 0 {
+		res.Err = fmt.Errorf("pids still alive after %d attempt(s): %s", res.Attempts, joinInts(remaining))
+	}
+	return res
+}
+
+func (e *Executor) sendAll(pids []int, sig syscall.Signal, attempt int, res *Result) {
+	for _, pid := range pids {
+		ev := SignalEvent{PID: pid, Signal: signalName(sig), Attempt: attempt, At: e.Clock.Now()}
+		if err := e.Signal(pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			ev.Error = err.Error()
+		}
+		res.Signals = append(res.Signals, ev)
+	}
+}
+
+func (e *Executor) waitGone(ctx context.Context, pids []int, wait time.Duration) []int {
+	deadline := time.Now().Add(wait)
+	remaining := e.filterAlive(pids)
+	for len(remaining) > 0 && time.Now().Before(deadline) {
+		t := time.NewTimer(e.PollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return remaining
+		case <-t.C:
+		}
+		remaining = e.filterAlive(remaining)
+	}
+	return remaining
+}
+
+func (e *Executor) filterAlive(pids []int) []int {
+	out := make([]int, 0, len(pids))
+	for _, pid := range pids {
+		if e.alive(pid) {
+			out = append(out, pid)
+		}
+	}
+	return out
+}
+
+// alive reports whether pid exists and is not a zombie. A zombie has
+// already released its CUDA context; it only waits to be reaped by its parent.
+func (e *Executor) alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if err := e.Signal(pid, 0); err != nil && errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	b, err := os.ReadFile(filepath.Join(e.ProcRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	// Format: pid (comm) state ...; comm may contain spaces/parens.
+	s := string(b)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 || i+2 >= len(s) {
+		return true
+	}
+	return s[i+2] != 'Z' && s[i+2] != 'X'
+}
+
+// startTime returns pid's start time (field 22 of /proc/<pid>/stat, in
+// clock ticks since boot) as read, or "" when it cannot be read. A PID
+// whose start time changed was reused by another process.
+func (e *Executor) startTime(pid int) string {
+	b, err := os.ReadFile(filepath.Join(e.ProcRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ""
+	}
+	s := string(b)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return ""
+	}
+	// Fields after comm start at field 3 (state).
+	const field = 22 - 3
+	f := strings.Fields(s[i+1:])
+	if len(f) <= field {
+		return ""
+	}
+	return f[field]
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGTERM:
+		return "TERM"
+	case syscall.SIGKILL:
+		return "KILL"
+	default:
+		return sig.String()
+	}
+}
+
+func joinInts(in []int) string {
+	s := append([]int(nil), in...)
+	sort.Ints(s)
+	parts := make([]string, 0, len(s))
+	for _, v := range s {
+		parts = append(parts, fmt.Sprint(v))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,325 @@
+package reclaim
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/clock"
+)
+
+// fakeProc is how a process reacts to signals.
+type fakeProc struct {
+	// exitAfter is the number of TERM/KILL signals it takes to exit; 0
+	// never exits (e.g. stuck in uninterruptible sleep).
+	exitAfter int
+	// vanish makes it exit on its own just before the first signal lands,
+	// which then fails with ESRCH.
+	vanish bool
+	// eperm makes every signal fail with EPERM.
+	eperm bool
+	// zombie has exited but is not reaped yet.
+	zombie bool
+	// reuseAfter makes it exit after that many signals, its PID at once
+	// reused by a new process that ignores signals.
+	reuseAfter int
+}
+
+// fakeProcs is a process table behind a synthetic procfs.
+type fakeProcs struct {
+	t    *testing.T
+	root string
+
+	mu       sync.Mutex
+	procs    map[int]*fakeProc
+	received map[int]int
+}
+
+func newFakeProcs(t *testing.T, procs map[int]*fakeProc) *fakeProcs {
+	f := &fakeProcs{t: t, root: t.TempDir(), procs: procs, received: map[int]int{}}
+	for pid, p := range procs {
+		state := "S"
+		if p.zombie {
+			state = "Z"
+		}
+		f.writeStat(pid, state, 1000)
+	}
+	return f
+}
+
+// writeStat writes /proc/<pid>/stat with the given state and start time.
+func (f *fakeProcs) writeStat(pid int, state string, start int) {
+	dir := filepath.Join(f.root, fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		f.t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (python (worker)) %s 1 %d %d 0 -1 4194304 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0\n", pid, state, pid, pid, start)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func (f *fakeProcs) exit(pid int) {
+	delete(f.procs, pid)
+	if err := os.RemoveAll(filepath.Join(f.root, fmt.Sprint(pid))); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func (f *fakeProcs) signal(pid int, sig syscall.Signal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.procs[pid]
+	if !ok {
+		return syscall.ESRCH
+	}
+	if sig == 0 {
+		return nil
+	}
+	if p.eperm {
+		return syscall.EPERM
+	}
+	if p.vanish {
+		f.exit(pid)
+		return syscall.ESRCH
+	}
+	f.received[pid]++
+	if p.exitAfter > 0 && f.received[pid] >= p.exitAfter {
+		f.exit(pid)
+	}
+	if p.reuseAfter > 0 && f.received[pid] >= p.reuseAfter {
+		f.procs[pid] = &fakeProc{}
+		f.writeStat(pid, "S", 2000)
+	}
+	return nil
+}
+
+func (f *fakeProcs) executor(maxRetry int) *Executor {
+	e := NewExecutor(20*time.Millisecond, maxRetry)
+	e.KillWait = 20 * time.Millisecond
+	e.PollInterval = 2 * time.Millisecond
+	e.Signal = f.signal
+	e.ProcRoot = f.root
+	return e
+}
+
+type sent struct {
+	PID     int
+	Signal  string
+	Attempt int
+	Error   string
+}
+
+func sentSignals(res Result) []sent {
+	out := []sent{}
+	for _, ev := range res.Signals {
+		out = append(out, sent{ev.PID, ev.Signal, ev.Attempt, ev.Error})
+	}
+	return out
+}
+
+func TestReclaim(t *testing.T) {
+	cases := []struct {
+		name      string
+		proc      fakeProc
+		maxRetry  int
+		want      []sent
+		attempts  int
+		remaining []int
+	}{
+		{
+			name:     "exits on TERM",
+			proc:     fakeProc{exitAfter: 1},
+			maxRetry: 2,
+			want:     []sent{{7, "TERM", 0, ""}},
+			attempts: 1,
+		},
+		{
+			name:     "escalates to KILL",
+			proc:     fakeProc{exitAfter: 2},
+			maxRetry: 2,
+			want:     []sent{{7, "TERM", 0, ""}, {7, "KILL", 0, ""}},
+			attempts: 1,
+		},
+		{
+			name:     "survives a round and exits on the retry",
+			proc:     fakeProc{exitAfter: 3},
+			maxRetry: 2,
+			want:     []sent{{7, "TERM", 0, ""}, {7, "KILL", 0, ""}, {7, "TERM", 1, ""}},
+			attempts: 2,
+		},
+		{
+			name:     "never exits",
+			proc:     fakeProc{},
+			maxRetry: 1,
+			want: []sent{
+				{7, "TERM", 0, ""}, {7, "KILL", 0, ""},
+				{7, "TERM", 1, ""}, {7, "KILL", 1, ""},
+			},
+			attempts:  2,
+			remaining: []int{7},
+		},
+		{
+			// ESRCH means the work is done, not an error.
+			name:     "exits before TERM lands",
+			proc:     fakeProc{vanish: true},
+			maxRetry: 2,
+			want:     []sent{{7, "TERM", 0, ""}},
+			attempts: 1,
+		},
+		{
+			name:     "zombie is not signalled",
+			proc:     fakeProc{zombie: true},
+			maxRetry: 2,
+			want:     []sent{},
+			attempts: 0,
+		},
+		{
+			name:     "not permitted",
+			proc:     fakeProc{eperm: true},
+			maxRetry: 0,
+			want: []sent{
+				{7, "TERM", 0, "operation not permitted"},
+				{7, "KILL", 0, "operation not permitted"},
+			},
+			attempts:  1,
+			remaining: []int{7},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := c.proc
+			f := newFakeProcs(t, map[int]*fakeProc{7: &p})
+			res := f.executor(c.maxRetry).Reclaim(context.Background(), []int{7}, nil)
+
+			if got := sentSignals(res); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("signals = %+v, want %+v", got, c.want)
+			}
+			if res.Attempts != c.attempts {
+				t.Errorf("attempts = %d, want %d", res.Attempts, c.attempts)
+			}
+			if fmt.Sprint(res.Remaining) != fmt.Sprint(c.remaining) {
+				t.Errorf("remaining = %v, want %v", res.Remaining, c.remaining)
+			}
+			if res.Success() != (len(c.remaining) == 0) {
+				t.Errorf("Success = %v with err %v", res.Success(), res.Err)
+			}
+		})
+	}
+}
+
+func TestReclaimAlreadyGone(t *testing.T) {
+	f := newFakeProcs(t, map[int]*fakeProc{})
+	res := f.executor(2).Reclaim(context.Background(), []int{7, 0}, nil)
+	if !res.Success() || res.Attempts != 0 || len(res.Signals) != 0 {
+		t.Errorf("result = %+v, want success without signals", res)
+	}
+}
+
+func TestReclaimOnlySignalsSurvivors(t *testing.T) {
+	f := newFakeProcs(t, map[int]*fakeProc{7: {exitAfter: 1}, 8: {exitAfter: 2}})
+	res := f.executor(2).Reclaim(context.Background(), []int{7, 8}, nil)
+	want := []sent{{7, "TERM", 0, ""}, {8, "TERM", 0, ""}, {8, "KILL", 0, ""}}
+	if got := sentSignals(res); !reflect.DeepEqual(got, want) {
+		t.Errorf("signals = %+v, want %+v", got, want)
+	}
+	if !res.Success() {
+		t.Errorf("reclaim failed: %v", res.Err)
+	}
+}
+
+func TestReclaimCanceled(t *testing.T) {
+	f := newFakeProcs(t, map[int]*fakeProc{7: {}})
+	e := f.executor(2)
+	e.Grace = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	res := e.Reclaim(ctx, []int{7}, nil)
+	if res.Err != context.Canceled || !reflect.DeepEqual(res.Remaining, []int{7}) {
+		t.Errorf("result = %+v, want canceled with pid 7 remaining", res)
+	}
+	if got := sentSignals(res); !reflect.DeepEqual(got, []sent{{7, "TERM", 0, ""}}) {
+		t.Errorf("signals = %+v, want TERM only", got)
+	}
+}
+
+func TestReclaimNeverSignalsReusedPIDs(t *testing.T) {
+	cases := []struct {
+		name string
+		proc fakeProc
+		// owned reports whether the PID still belongs to the pod, given
+		// how many signals it has received.
+		owned func(received int) bool
+		want  []sent
+	}{
+		{
+			name: "reused during the grace period",
+			proc: fakeProc{reuseAfter: 1},
+			want: []sent{{7, "TERM", 0, ""}},
+		},
+		{
+			name: "reused after KILL, before the retry",
+			proc: fakeProc{reuseAfter: 2},
+			want: []sent{{7, "TERM", 0, ""}, {7, "KILL", 0, ""}},
+		},
+		{
+			name:  "moved to another pod's cgroup",
+			proc:  fakeProc{},
+			owned: func(received int) bool { return received < 1 },
+			want:  []sent{{7, "TERM", 0, ""}},
+		},
+		{
+			name:  "not owned before the first signal",
+			proc:  fakeProc{},
+			owned: func(int) bool { return false },
+			want:  []sent{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := c.proc
+			f := newFakeProcs(t, map[int]*fakeProc{7: &p})
+			var owned func(int) bool
+			if c.owned != nil {
+				owned = func(pid int) bool {
+					f.mu.Lock()
+					defer f.mu.Unlock()
+					return c.owned(f.received[pid])
+				}
+			}
+			res := f.executor(2).Reclaim(context.Background(), []int{7}, owned)
+
+			if got := sentSignals(res); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("signals = %+v, want %+v", got, c.want)
+			}
+			if !reflect.DeepEqual(res.NotOwned, []int{7}) || len(res.Remaining) != 0 || !res.Success() {
+				t.Errorf("result = %+v, want pid 7 dropped as not owned", res)
+			}
+		})
+	}
+}
+
+func TestReclaimSignalTimesFollowClock(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	f := newFakeProcs(t, map[int]*fakeProc{7: {exitAfter: 2}})
+	e := f.executor(0)
+	e.Clock = clock.NewFake(t0)
+	res := e.Reclaim(context.Background(), []int{7}, nil)
+	if len(res.Signals) != 2 {
+		t.Fatalf("signals = %+v, want TERM and KILL", res.Signals)
+	}
+	for _, ev := range res.Signals {
+		if !ev.At.Equal(t0) {
+			t.Errorf("%s at %v, want the injected clock's %v", ev.Signal, ev.At, t0)
+		}
+	}
+}
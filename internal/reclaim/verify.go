@@ -0,0 +1,178 @@
+package reclaim
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+type VerifyStatus string
+
+const (
+	// Verified: every reclaimed PID left the GPU process lists and the
+	// memory they held is no longer counted as used.
+	Verified VerifyStatus = "verified"
+	// PartiallyFreed: the PIDs are gone but MemUsedBytes did not drop by
+	// what they held (e.g. a zombie or sibling still holding the context).
+	PartiallyFreed VerifyStatus = "partially_freed"
+	// StillPresent: at least one reclaimed PID is still listed on a GPU.
+	StillPresent VerifyStatus = "still_present"
+	// Unverified: no complete snapshot could be taken before the deadline.
+	Unverified VerifyStatus = "unverified"
+)
+
+// Verification is the post-reclaim check result for one candidate (PRD FR-9).
+type Verification struct {
+	Status       VerifyStatus
+	Polls        int
+	StillPresent []int
+	// Per device (GPUSnapshot.ID): bytes the reclaimed PIDs held before the
+	// kill, and how much MemUsedBytes actually dropped in the last snapshot.
+	ExpectedFreedBytes map[string]uint64
+	ObservedFreedBytes map[string]int64
+	Err                error
+}
+
+// Verifier re-samples GPUs after a reclaim until the reclaimed PIDs and their
+// memory are gone, or Timeout elapses.
+type Verifier struct {
+	Sampler  sampling.Sampler
+	Timeout  time.Duration
+	Interval time.Duration
+	// SlackBytes tolerates allocator noise from other processes on the card.
+	SlackBytes uint64
+}
+
+func NewVerifier(s sampling.Sampler, timeout time.Duration) *Verifier {
+	return &Verifier{
+		Sampler:    s,
+		Timeout:    timeout,
+		Interval:   2 * time.Second,
+		SlackBytes: 64 << 20,
+	}
+}
+
+// Verify compares fresh snapshots against before, the snapshot taken just
+// prior to signalling pids.
+func (v *Verifier) Verify(ctx context.Context, before sampling.Snapshot, pids []int) Verification {
+	expected := expectedFreed(before, pids)
+	res := Verification{Status: Unverified, ExpectedFreedBytes: expected}
+
+	vctx, cancel := context.WithTimeout(ctx, v.Timeout)
+	defer cancel()
+
+	for {
+		snap, err := v.Sampler.Sample(vctx)
+		if err == nil {
+			if bad := snap.Unhealthy(); len(bad) > 0 {
+				// A PID missing from an unreadable device is not gone.
+				err = fmt.Errorf("gpu %s unhealthy: %s", bad[0].ID(), bad[0].Error)
+			}
+		}
+		if err != nil {
+			res.Err = err
+		} else {
+			res.Polls++
+			res.Err = nil
+			res.StillPresent = presentPIDs(snap, pids)
+			res.ObservedFreedBytes = observedFreed(before, snap, expected)
+			switch {
+			case len(res.StillPresent) > 0:
+				res.Status = StillPresent
+			case !v.memoryReleased(expected, res.ObservedFreedBytes):
+				res.Status = PartiallyFreed
+			default:
+				res.Status = Verified
+				return res
+			}
+		}
+
+		t := time.NewTimer(v.Interval)
+		select {
+		case <-vctx.Done():
+			t.Stop()
+			return res
+		case <-t.C:
+		}
+	}
+}
+
+func (v *Verifier) memoryReleased(expected map[string]uint64, observed map[string]int64) bool {
+	for gi, want := range expected {
+		got := observed[gi]
+		if got+int64(v.SlackBytes) < int64(want) {
+			return false
+		}
+	}
+	return true
+}
+
+func expectedFreed(before sampling.Snapshot, pids []int) map[string]uint64 {
+	pidSet := intSet(pids)
+	out := map[string]uint64{}
+	for _, g := range before.GPUs {
+		for _, p := range g.ComputeProcs {
+			if _, ok := pidSet[p.PID]; !ok {
+				continue
+			}
+			// Some drivers report "not available" as a huge sentinel; ignore it.
+			if p.UsedBytesUnknown || (g.MemTotalBytes > 0 && p.UsedBytes > g.MemTotalBytes) {
+				continue
+			}
+			out[g.ID()] += p.UsedBytes
+		}
+	}
+	return out
+}
+
+func observedFreed(before, after sampling.Snapshot, expected map[string]uint64) map[string]int64 {
+	out := map[string]int64{}
+	for id := range expected {
+		b, okB := deviceByID(before, id)
+		a, okA := deviceByID(after, id)
+		if !okB || !okA || b.MemUsedUnknown || a.MemUsedUnknown {
+			// Not observed; memoryReleased then treats it as not freed.
+			continue
+		}
+		out[id] = int64(b.MemUsedBytes) - int64(a.MemUsedBytes)
+	}
+	return out
+}
+
+func presentPIDs(snap sampling.Snapshot, pids []int) []int {
+	pidSet := intSet(pids)
+	seen := map[int]struct{}{}
+	for _, g := range snap.GPUs {
+		for _, p := range g.ComputeProcs {
+			if _, ok := pidSet[p.PID]; ok {
+				seen[p.PID] = struct{}{}
+			}
+		}
+	}
+	out := make([]int, 0, len(seen))
+	for _, pid := range pids {
+		if _, ok := seen[pid]; ok {
+			out = append(out, pid)
+		}
+	}
+	return out
+}
+
+func deviceByID(snap sampling.Snapshot, id string) (sampling.GPUSnapshot, bool) {
+	for _, g := range snap.GPUs {
+		if g.ID() == id {
+			return g, true
+		}
+	}
+	return sampling.GPUSnapshot{}, false
+}
+
+func intSet(in []int) map[int]struct{} {
+	out := make(map[int]struct{}, len(in))
+	for _, v := range in {
+		out[v] = struct{}{}
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package reclaim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+const mib = 1 << 20
+
+// scriptedSampler returns its snapshots in order, then repeats the last.
+type scriptedSampler struct {
+	mu    sync.Mutex
+	snaps []sampling.Snapshot
+	errs  []error
+	calls int
+}
+
+func (s *scriptedSampler) Name() string { return "scripted" }
+func (s *scriptedSampler) Close() error { return nil }
+
+func (s *scriptedSampler) Sample(context.Context) (sampling.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.calls
+	if i >= len(s.snaps) {
+		i = len(s.snaps) - 1
+	}
+	s.calls++
+	var err error
+	if i < len(s.errs) {
+		err = s.errs[i]
+	}
+	return s.snaps[i], err
+}
+
+// card is GPU 0 with 80GiB, usedMiB in use and the given processes.
+func card(usedMiB uint64, procs ...sampling.GPUProcess) sampling.Snapshot {
+	return sampling.Snapshot{GPUs: []sampling.GPUSnapshot{{
+		Index: 0, UUID: "GPU-0", MemUsedBytes: usedMiB * mib, MemTotalBytes: 81920 * mib, ComputeProcs: procs,
+	}}}
+}
+
+func TestVerify(t *testing.T) {
+	// PID 7 holds 10GiB of the 12GiB in use; PID 8 is someone else's.
+	before := card(12288, sampling.GPUProcess{PID: 7, UsedBytes: 10240 * mib}, sampling.GPUProcess{PID: 8, UsedBytes: 2048 * mib})
+	other := sampling.GPUProcess{PID: 8, UsedBytes: 2048 * mib}
+
+	cases := []struct {
+		name         string
+		snaps        []sampling.Snapshot
+		errs         []error
+		status       VerifyStatus
+		stillPresent []int
+		observed     int64
+		polls        int
+		wantErr      bool
+	}{
+		{
+			name:     "freed",
+			snaps:    []sampling.Snapshot{card(2048, other)},
+			status:   Verified,
+			observed: 10240 * mib,
+			polls:    1,
+		},
+		{
+			name:     "freed on a later poll",
+			snaps:    []sampling.Snapshot{before, card(2048, other)},
+			status:   Verified,
+			observed: 10240 * mib,
+			polls:    2,
+		},
+		{
+			// Other processes allocated a little in the meantime.
+			name:     "within slack",
+			snaps:    []sampling.Snapshot{card(2048+40, other)},
+			status:   Verified,
+			observed: (10240 - 40) * mib,
+			polls:    1,
+		},
+		{
+			name:     "beyond slack",
+			snaps:    []sampling.Snapshot{card(2048+100, other)},
+			status:   PartiallyFreed,
+			observed: (10240 - 100) * mib,
+		},
+		{
+			name:         "still present",
+			snaps:        []sampling.Snapshot{before},
+			status:       StillPresent,
+			stillPresent: []int{7},
+		},
+		{
+			// A PID missing from an unreadable GPU proves nothing.
+			name:    "unhealthy gpu",
+			snaps:   []sampling.Snapshot{{GPUs: []sampling.GPUSnapshot{sampling.UnhealthyGPU(0, "GPU-0", "GPU is lost")}}},
+			status:  Unverified,
+			wantErr: true,
+		},
+		{
+			name:    "sampler fails",
+			snaps:   []sampling.Snapshot{{}},
+			errs:    []error{errors.New("nvml: driver not loaded")},
+			status:  Unverified,
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := NewVerifier(&scriptedSampler{snaps: c.snaps, errs: c.errs}, 50*time.Millisecond)
+			v.Interval = time.Millisecond
+			res := v.Verify(context.Background(), before, []int{7})
+
+			if res.Status != c.status {
+				t.Errorf("status = %s, want %s", res.Status, c.status)
+			}
+			if !reflect.DeepEqual(res.ExpectedFreedBytes, map[string]uint64{"0": 10240 * mib}) {
+				t.Errorf("expected freed = %v", res.ExpectedFreedBytes)
+			}
+			if len(c.stillPresent) > 0 && !reflect.DeepEqual(res.StillPresent, c.stillPresent) {
+				t.Errorf("still present = %v, want %v", res.StillPresent, c.stillPresent)
+			}
+			if c.observed != 0 && res.ObservedFreedBytes["0"] != c.observed {
+				t.Errorf("observed freed = %d, want %d", res.ObservedFreedBytes["0"], c.observed)
+			}
+			if c.polls != 0 && res.Polls != c.polls {
+				t.Errorf("polls = %d, want %d", res.Polls, c.polls)
+			}
+			if (res.Err != nil) != c.wantErr {
+				t.Errorf("err = %v, want error %v", res.Err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectedFreedIgnoresUnknownUsage(t *testing.T) {
+	before := card(4096,
+		sampling.GPUProcess{PID: 7, UsedBytes: 1024 * mib},
+		sampling.GPUProcess{PID: 9, UsedBytesUnknown: true},
+		// Some drivers report "not available" as a huge sentinel.
+		sampling.GPUProcess{PID: 10, UsedBytes: 1 << 62},
+	)
+	got := expectedFreed(before, []int{7, 9, 10})
+	if want := map[string]uint64{"0": 1024 * mib}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expectedFreed = %v, want %v", got, want)
+	}
+}
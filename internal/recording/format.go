@@ -0,0 +1,100 @@
+// Package recording reads and writes JSONL recordings of the snapshots the
+// agent sampled and the PID attributions it resolved, so a decision seen in
+// production can be inspected and replayed offline.
+package recording
+
+import (
+	"context"
+	"time"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// FormatVersion is written into every record; replay refuses records with a
+// different version instead of misreading them.
+const FormatVersion = 1
+
+// Record kinds tell what a snapshot was sampled for or what an attribution
+// was resolved for. Recordings made before kinds existed leave Kind empty.
+const (
+	// KindTick is the per-interval sample and the attributions of its PIDs.
+	KindTick = "tick"
+	// KindValidate is the re-sample validating a candidate.
+	KindValidate = "validate"
+	// KindVerify is a poll checking that a reclaim released the GPU.
+	KindVerify = "verify"
+	// KindReclaim is an ownership re-check made while reclaiming.
+	KindReclaim = "reclaim"
+)
+
+type kindKey struct{}
+
+// WithKind tags the samples and attributions made with ctx as kind.
+func WithKind(ctx context.Context, kind string) context.Context {
+	return context.WithValue(ctx, kindKey{}, kind)
+}
+
+// KindOf returns the kind ctx was tagged with, KindTick by default.
+func KindOf(ctx context.Context) string {
+	if k, ok := ctx.Value(kindKey{}).(string); ok {
+		return k
+	}
+	return KindTick
+}
+
+// Record is one line of a recording: either a snapshot or one PID
+// attribution. Attributions follow the snapshot they were resolved for,
+// except reclaim re-checks, which run alongside later ticks.
+type Record struct {
+	Version     int                `json:"v"`
+	Time        time.Time          `json:"time"`
+	Kind        string             `json:"kind,omitempty"`
+	Sampler     string             `json:"sampler,omitempty"`
+	Snapshot    *sampling.Snapshot `json:"snapshot,omitempty"`
+	Attribution *Attribution       `json:"attribution,omitempty"`
+}
+
+// Attribution is the recorded outcome of resolving one PID, including
+// failures: a PID that could not be attributed is evidence too.
+type Attribution struct {
+	PID           int    `json:"pid"`
+	PodUID        string `json:"podUID,omitempty"`
+	PodNamespace  string `json:"podNamespace,omitempty"`
+	PodName       string `json:"podName,omitempty"`
+	ContainerName string `json:"containerName,omitempty"`
+	ContainerID   string `json:"containerID,omitempty"`
+	Cmdline       string `json:"cmdline,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+func fromAttribution(pid int, a attribution.Attribution, err error) *Attribution {
+	out := &Attribution{
+		PID:           pid,
+		PodUID:        a.PodUID,
+		PodNamespace:  a.PodNamespace,
+		PodName:       a.PodName,
+		ContainerName: a.ContainerName,
+		ContainerID:   a.ContainerID,
+		Cmdline:       a.Cmdline,
+		Source:        a.Source,
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	return out
+}
+
+func (r *Attribution) toAttribution() attribution.Attribution {
+	return attribution.Attribution{
+		PID:           r.PID,
+		PodUID:        r.PodUID,
+		PodNamespace:  r.PodNamespace,
+		PodName:       r.PodName,
+		ContainerName: r.ContainerName,
+		ContainerID:   r.ContainerID,
+		Cmdline:       r.Cmdline,
+		Source:        r.Source,
+	}
+}
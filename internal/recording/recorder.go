@@ -0,0 +1,86 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/clock"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// Resolver is the PID attribution interface the agent uses.
+type Resolver interface {
+	ResolvePID(ctx context.Context, pid int) (attribution.Attribution, error)
+}
+
+// Recorder wraps a live sampler and appends every successful snapshot to w
+// as a Record; Attributor does the same for PID attributions. Each record
+// carries the kind its context was tagged with (see WithKind). Recording is
+// best-effort: a write error is reported through OnError and never fails
+// the sample or the lookup.
+type Recorder struct {
+	inner sampling.Sampler
+	w     io.Writer
+
+	// OnError, when set, is called for every failed write.
+	OnError func(error)
+	// Clock stamps records (default wall time).
+	Clock clock.Clock
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewRecorder(inner sampling.Sampler, w io.Writer) *Recorder {
+	return &Recorder{inner: inner, w: w, Clock: clock.Real{}, enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) Name() string { return r.inner.Name() }
+
+// Close closes the wrapped sampler and, if it is a Closer, the writer.
+func (r *Recorder) Close() error {
+	err := r.inner.Close()
+	if c, ok := r.w.(io.Closer); ok {
+		if cErr := c.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+func (r *Recorder) Sample(ctx context.Context) (sampling.Snapshot, error) {
+	snap, err := r.inner.Sample(ctx)
+	if err != nil {
+		return snap, err
+	}
+	r.write(Record{Version: FormatVersion, Time: r.Clock.Now().UTC(), Kind: KindOf(ctx), Sampler: r.inner.Name(), Snapshot: &snap})
+	return snap, nil
+}
+
+// Attributor wraps inner so every ResolvePID outcome is recorded after the
+// snapshot it belongs to.
+func (r *Recorder) Attributor(inner Resolver) Resolver {
+	return recordingResolver{rec: r, inner: inner}
+}
+
+type recordingResolver struct {
+	rec   *Recorder
+	inner Resolver
+}
+
+func (rr recordingResolver) ResolvePID(ctx context.Context, pid int) (attribution.Attribution, error) {
+	attr, err := rr.inner.ResolvePID(ctx, pid)
+	rr.rec.write(Record{Version: FormatVersion, Time: rr.rec.Clock.Now().UTC(), Kind: KindOf(ctx), Attribution: fromAttribution(pid, attr, err)})
+	return attr, err
+}
+
+func (r *Recorder) write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(rec); err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
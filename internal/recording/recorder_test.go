@@ -0,0 +1,83 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/clock"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+type staticSampler struct{}
+
+func (staticSampler) Name() string { return "nvml" }
+func (staticSampler) Close() error { return nil }
+func (staticSampler) Sample(context.Context) (sampling.Snapshot, error) {
+	return sampling.Snapshot{GPUs: []sampling.GPUSnapshot{{Index: 0, UUID: "GPU-0"}}}, nil
+}
+
+type staticResolver struct{}
+
+func (staticResolver) ResolvePID(_ context.Context, pid int) (attribution.Attribution, error) {
+	return attribution.Attribution{PID: pid, PodUID: "uid-a"}, nil
+}
+
+func TestRecorderTagsKindAndFollowsClock(t *testing.T) {
+	var buf bytes.Buffer
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	clk := clock.NewFake(t0)
+	rec := NewRecorder(staticSampler{}, &buf)
+	rec.Clock = clk
+	res := rec.Attributor(staticResolver{})
+	ctx := context.Background()
+
+	if _, err := rec.Sample(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := res.ResolvePID(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Second)
+	if _, err := rec.Sample(WithKind(ctx, KindValidate)); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Second)
+	if _, err := res.ResolvePID(WithKind(ctx, KindReclaim), 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Sample(WithKind(ctx, KindVerify)); err != nil {
+		t.Fatal(err)
+	}
+
+	type line struct {
+		kind     string
+		at       time.Time
+		snapshot bool
+	}
+	want := []line{
+		{KindTick, t0, true},
+		{KindTick, t0, false},
+		{KindValidate, t0.Add(time.Second), true},
+		{KindReclaim, t0.Add(2 * time.Second), false},
+		{KindVerify, t0.Add(2 * time.Second), true},
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("%d records, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, l := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			t.Fatal(err)
+		}
+		got := line{r.Kind, r.Time, r.Snapshot != nil}
+		if got.kind != want[i].kind || !got.at.Equal(want[i].at) || got.at.Location() != time.UTC || got.snapshot != want[i].snapshot {
+			t.Errorf("record %d = %+v, want %+v in UTC", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gpu-reclaimer-agent/internal/attribution"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// Replay is a sampling.Sampler that returns recorded snapshots in order.
+// A Sample call consumes the next snapshot of the kind its context is
+// tagged with (see WithKind): ticks replay ticks and candidate validation
+// replays the recorded validation re-sample, so a recording made by the
+// same agent version replays the same decisions. Snapshots of other kinds
+// are skipped, notably the verify polls of an enforced reclaim, which a
+// dry-run replay never takes. A validation the recorded agent did not make,
+// including one past the end of the recording, sees the last tick's
+// snapshot again. Snapshots without a kind, from recordings made before
+// kinds existed, match any call. Sample returns io.EOF once the recording
+// is exhausted.
+//
+// Replay also answers ResolvePID from the attributions recorded after the
+// current tick, so replay needs neither the original processes nor /proc.
+// Reclaim ownership re-checks recorded in between are skipped.
+//
+// Files rotated away by RotatingWriter (path.1, path.2, ...) are replayed
+// first, oldest first, followed by path itself.
+//
+// With a virtual clock, Now reports the time of the last replayed snapshot
+// instead of wall time, so a 30-minute idle window replays instantly.
+type Replay struct {
+	virtual bool
+
+	mu sync.Mutex
+	// Files still to read, oldest first; path is the one being read.
+	files   []string
+	path    string
+	f       *os.File
+	sc      *bufio.Scanner
+	line    int
+	pending *Record
+	now     time.Time
+	attrs   map[int]*Attribution
+	// The last tick snapshot, for validations not in the recording.
+	lastTick sampling.Snapshot
+}
+
+// OpenReplay opens a JSONL recording, with its rotated files, for replay.
+func OpenReplay(path string, virtualClock bool) (*Replay, error) {
+	var rotated []string
+	for i := 1; ; i++ {
+		p := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		rotated = append([]string{p}, rotated...)
+	}
+	r := &Replay{virtual: virtualClock, files: append(rotated, path), attrs: map[int]*Attribution{}}
+	if err := r.openNext(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// openNext closes the current file and opens the next one.
+func (r *Replay) openNext() error {
+	if r.f != nil {
+		_ = r.f.Close()
+	}
+	f, err := os.Open(r.files[0])
+	if err != nil {
+		return err
+	}
+	r.path, r.files = r.files[0], r.files[1:]
+	r.f, r.line = f, 0
+	r.sc = bufio.NewScanner(f)
+	r.sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return nil
+}
+
+func (r *Replay) Name() string { return "replay" }
+
+func (r *Replay) Close() error { return r.f.Close() }
+
+// VirtualClock reports whether Now follows the recording.
+func (r *Replay) VirtualClock() bool { return r.virtual }
+
+// Now returns the time of the most recently replayed snapshot, or wall time
+// when the virtual clock is off or nothing was replayed yet.
+func (r *Replay) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.virtual || r.now.IsZero() {
+		return time.Now()
+	}
+	return r.now
+}
+
+func (r *Replay) Sample(ctx context.Context) (sampling.Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return sampling.Snapshot{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want := KindOf(ctx)
+	var snap *Record
+	for snap == nil {
+		rec, err := r.next()
+		if errors.Is(err, io.EOF) && want == KindValidate {
+			return r.lastTick, nil
+		}
+		if err != nil {
+			return sampling.Snapshot{}, err
+		}
+		switch {
+		case rec.Snapshot == nil:
+			// Attributions before the first snapshot, or of a skipped one,
+			// have nothing to belong to.
+		case rec.Kind == "" || rec.Kind == want:
+			snap = rec
+		case want == KindValidate && rec.Kind == KindTick:
+			// The recorded agent did not validate here.
+			r.pending = rec
+			return r.lastTick, nil
+		}
+	}
+	tick := snap.Kind == "" || snap.Kind == KindTick
+	r.now = snap.Time
+	if !tick {
+		// Attributions keep answering for the tick being evaluated.
+		return *snap.Snapshot, nil
+	}
+
+	// Collect the attributions resolved for this snapshot, stopping at the
+	// next snapshot which stays pending for the following call.
+	r.attrs = map[int]*Attribution{}
+	for {
+		rec, err := r.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return sampling.Snapshot{}, err
+		}
+		if rec.Snapshot != nil {
+			r.pending = rec
+			break
+		}
+		if rec.Attribution != nil && rec.Kind != KindReclaim {
+			r.attrs[rec.Attribution.PID] = rec.Attribution
+		}
+	}
+	r.lastTick = *snap.Snapshot
+	return r.lastTick, nil
+}
+
+// ResolvePID returns the attribution recorded for pid after the current
+// snapshot, including a recorded failure.
+func (r *Replay) ResolvePID(_ context.Context, pid int) (attribution.Attribution, error) {
+	r.mu.Lock()
+	rec, ok := r.attrs[pid]
+	r.mu.Unlock()
+	if !ok {
+		return attribution.Attribution{}, fmt.Errorf("pid %d has no recorded attribution", pid)
+	}
+	if rec.Error != "" {
+		return attribution.Attribution{}, errors.New(rec.Error)
+	}
+	return rec.toAttribution(), nil
+}
+
+func (r *Replay) next() (*Record, error) {
+	if rec := r.pending; rec != nil {
+		r.pending = nil
+		return rec, nil
+	}
+	for {
+		if !r.sc.Scan() {
+			if err := r.sc.Err(); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", r.path, r.line, err)
+			}
+			if len(r.files) == 0 {
+				return nil, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r.line++
+		b := r.sc.Bytes()
+		if len(b) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", r.path, r.line, err)
+		}
+		if rec.Version != FormatVersion {
+			return nil, fmt.Errorf("%s:%d: record version %d not supported (want %d)", r.path, r.line, rec.Version, FormatVersion)
+		}
+		return &rec, nil
+	}
+}
@@ -0,0 +1,194 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRecording(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplayReturnsSnapshotsThenEOF(t *testing.T) {
+	path := writeRecording(t,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","attribution":{"pid":7}}`,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":3,"computeProcs":[{"pid":7,"usedBytes":1024}]}]}}`,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","attribution":{"pid":7,"podUID":"uid-a","podName":"train-0"}}`,
+		``,
+		`{"v":1,"time":"2026-03-01T09:01:00Z","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0"}]}}`,
+		`{"v":1,"time":"2026-03-01T09:01:00Z","attribution":{"pid":7,"error":"pid 7 exited"}}`,
+	)
+	r, err := OpenReplay(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+
+	snap, err := r.Sample(ctx)
+	if err != nil {
+		t.Fatalf("Sample 1: %v", err)
+	}
+	if len(snap.GPUs) != 1 || snap.GPUs[0].UtilGPU != 3 || len(snap.GPUs[0].ComputeProcs) != 1 {
+		t.Errorf("snapshot 1 = %+v", snap)
+	}
+	if want := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC); !r.Now().Equal(want) {
+		t.Errorf("Now = %v, want %v", r.Now(), want)
+	}
+	// The attribution before the first snapshot is dropped.
+	attr, err := r.ResolvePID(ctx, 7)
+	if err != nil || attr.PodUID != "uid-a" || attr.PodName != "train-0" {
+		t.Errorf("ResolvePID after snapshot 1 = %+v, %v", attr, err)
+	}
+
+	if _, err := r.Sample(ctx); err != nil {
+		t.Fatalf("Sample 2: %v", err)
+	}
+	if _, err := r.ResolvePID(ctx, 7); err == nil || err.Error() != "pid 7 exited" {
+		t.Errorf("ResolvePID after snapshot 2 err = %v, want the recorded failure", err)
+	}
+	if _, err := r.ResolvePID(ctx, 8); err == nil {
+		t.Error("ResolvePID of an unrecorded pid succeeded")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Sample(ctx); !errors.Is(err, io.EOF) {
+			t.Fatalf("Sample past the end err = %v, want io.EOF", err)
+		}
+	}
+}
+
+func TestReplayRejectsOtherVersion(t *testing.T) {
+	path := writeRecording(t,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","snapshot":{"gpus":[]}}`,
+		`{"v":2,"time":"2026-03-01T09:01:00Z","snapshot":{"gpus":[]}}`,
+	)
+	r, err := OpenReplay(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Reading ahead for snapshot 1's attributions hits the v2 record.
+	_, err = r.Sample(context.Background())
+	if err == nil || errors.Is(err, io.EOF) || !strings.Contains(err.Error(), ":2: record version 2 not supported (want 1)") {
+		t.Fatalf("Sample err = %v, want version error at line 2", err)
+	}
+}
+
+func TestReplayRejectsMalformedLine(t *testing.T) {
+	r, err := OpenReplay(writeRecording(t, `{"v":1,"snapshot":`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Sample(context.Background()); err == nil || errors.Is(err, io.EOF) || !strings.Contains(err.Error(), ":1: ") {
+		t.Fatalf("Sample err = %v, want parse error at line 1", err)
+	}
+}
+
+func TestReplayNowWithoutVirtualClock(t *testing.T) {
+	r, err := OpenReplay(writeRecording(t, `{"v":1,"time":"2020-01-01T00:00:00Z","snapshot":{"gpus":[]}}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Sample(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r.Now().Year() == 2020 {
+		t.Errorf("Now = %v follows the recording with the virtual clock off", r.Now())
+	}
+}
+
+func TestReplayKeysOnRecordKind(t *testing.T) {
+	path := writeRecording(t,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","kind":"tick","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":1}]}}`,
+		`{"v":1,"time":"2026-03-01T09:00:00Z","kind":"tick","attribution":{"pid":7,"podUID":"uid-a"}}`,
+		`{"v":1,"time":"2026-03-01T09:00:01Z","kind":"validate","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":2}]}}`,
+		`{"v":1,"time":"2026-03-01T09:00:02Z","kind":"reclaim","attribution":{"pid":7,"error":"pid 7 exited"}}`,
+		`{"v":1,"time":"2026-03-01T09:00:03Z","kind":"verify","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":3}]}}`,
+		`{"v":1,"time":"2026-03-01T09:01:00Z","kind":"tick","snapshot":{"gpus":[{"index":0,"uuid":"GPU-0","utilGPU":4}]}}`,
+	)
+	r, err := OpenReplay(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+	validate := WithKind(ctx, KindValidate)
+
+	util := func(c context.Context) uint32 {
+		t.Helper()
+		snap, err := r.Sample(c)
+		if err != nil {
+			t.Fatalf("Sample(%s): %v", KindOf(c), err)
+		}
+		return snap.GPUs[0].UtilGPU
+	}
+	if got := util(ctx); got != 1 {
+		t.Errorf("tick 1 util = %d, want 1", got)
+	}
+	if got := util(validate); got != 2 {
+		t.Errorf("validation util = %d, want the recorded re-sample 2", got)
+	}
+	// The reclaim re-check does not replace the tick's attribution.
+	if attr, err := r.ResolvePID(ctx, 7); err != nil || attr.PodUID != "uid-a" {
+		t.Errorf("ResolvePID after validation = %+v, %v", attr, err)
+	}
+	// The verify poll is skipped.
+	if got := util(ctx); got != 4 {
+		t.Errorf("tick 2 util = %d, want 4", got)
+	}
+	if want := time.Date(2026, 3, 1, 9, 1, 0, 0, time.UTC); !r.Now().Equal(want) {
+		t.Errorf("Now = %v, want %v", r.Now(), want)
+	}
+	// A validation the recording lacks, even at its end, sees the last tick
+	// again.
+	if got := util(validate); got != 4 {
+		t.Errorf("unrecorded validation util = %d, want the last tick's 4", got)
+	}
+	if _, err := r.Sample(ctx); !errors.Is(err, io.EOF) {
+		t.Fatalf("Sample past the end err = %v, want io.EOF", err)
+	}
+}
+
+func TestReplayFollowsRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	for p, util := range map[string]string{path + ".2": "3", path + ".1": "2", path: "1"} {
+		line := `{"v":1,"time":"2026-03-01T09:00:00Z","kind":"tick","snapshot":{"gpus":[{"index":0,"utilGPU":` + util + `}]}}` + "\n"
+		if err := os.WriteFile(p, []byte(line), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := OpenReplay(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// path.2 is the oldest file and path the newest.
+	for _, want := range []uint32{3, 2, 1} {
+		snap, err := r.Sample(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := snap.GPUs[0].UtilGPU; got != want {
+			t.Errorf("util = %d, want %d", got, want)
+		}
+	}
+	if _, err := r.Sample(context.Background()); !errors.Is(err, io.EOF) {
+		t.Fatalf("Sample past the end err = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,109 @@
+package recording
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingWriter appends to path and, once the file would grow past
+// maxBytes, shifts path -> path.1 -> ... -> path.<maxFiles-1>, dropping the
+// oldest. A single Write is never split across files, so every file holds
+// whole JSONL lines.
+type RotatingWriter struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	// OnRotateError, when set, is told about a failed shift of the older
+	// files. The write itself still goes to a fresh live file.
+	OnRotateError func(error)
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewRotatingWriter opens (appending to) path. maxFiles counts the live file.
+func NewRotatingWriter(path string, maxBytes int64, maxFiles int) (*RotatingWriter, error) {
+	if maxFiles < 1 {
+		maxFiles = 1
+	}
+	w := &RotatingWriter{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f, w.size = f, fi.Size()
+	return nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		shiftErr, err := w.rotate()
+		if err != nil {
+			return 0, err
+		}
+		if shiftErr != nil && w.OnRotateError != nil {
+			w.OnRotateError(shiftErr)
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate starts a new live file. shiftErr is the first failure to move an
+// older file along; err means there is no live file to write to.
+func (w *RotatingWriter) rotate() (shiftErr, err error) {
+	if err := w.f.Close(); err != nil {
+		return nil, err
+	}
+	w.f = nil
+	if w.maxFiles == 1 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, w.open()
+	}
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		src := w.path
+		if i > 1 {
+			src = fmt.Sprintf("%s.%d", w.path, i-1)
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", w.path, i)); err != nil && !os.IsNotExist(err) && shiftErr == nil {
+			shiftErr = err
+		}
+	}
+	// Keep writing even if a shift failed; losing history beats losing
+	// the current evidence.
+	return shiftErr, w.open()
+}
+
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
@@ -0,0 +1,56 @@
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	w, err := NewRotatingWriter(path, 10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, line := range []string{"aaaaaaa\n", "bbbbbbb\n", "ccccccc\n", "ddddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	for name, want := range map[string]string{"": "ddddddd\n", ".1": "ccccccc\n", ".2": "bbbbbbb\n"} {
+		b, err := os.ReadFile(path + name)
+		if err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v; want %q", path+name, b, err, want)
+		}
+	}
+}
+
+func TestRotatingWriterWritesWhenShiftFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	// A non-empty directory where path.1 should go makes the shift fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var shiftErrs []error
+	w.OnRotateError = func(err error) { shiftErrs = append(shiftErrs, err) }
+
+	for _, line := range []string{"aaaaaaa\n", "bbbbbbb\n"} {
+		if n, err := w.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write(%q) = %d, %v", line, n, err)
+		}
+	}
+	if len(shiftErrs) != 1 {
+		t.Errorf("OnRotateError called %d times, want 1", len(shiftErrs))
+	}
+	b, _ := os.ReadFile(path)
+	if !strings.Contains(string(b), "bbbbbbb") {
+		t.Errorf("live file = %q, want the second record", b)
+	}
+}
@@ -0,0 +1,78 @@
+package sampling
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gpu-reclaimer-agent/internal/clock"
+)
+
+// DefaultRetryAfter is how long Fallback stays on its secondary sampler
+// before trying the primary again.
+const DefaultRetryAfter = 5 * time.Minute
+
+// Fallback samples from Primary and switches to Secondary when Primary
+// fails (e.g. NVML cannot initialize after a driver upgrade). Primary is
+// retried after RetryAfter, so the agent returns to it once it recovers.
+type Fallback struct {
+	Primary    Sampler
+	Secondary  Sampler
+	RetryAfter time.Duration
+	Clock      clock.Clock
+
+	// OnSwitch is called when the active sampler changes; err is the
+	// primary's error when falling back and nil when returning to it.
+	OnSwitch func(active string, err error)
+
+	mu          sync.Mutex
+	onSecondary bool
+	retryAt     time.Time
+}
+
+func NewFallback(primary, secondary Sampler) *Fallback {
+	return &Fallback{Primary: primary, Secondary: secondary, RetryAfter: DefaultRetryAfter, Clock: clock.Real{}}
+}
+
+func (f *Fallback) Name() string { return "auto" }
+
+func (f *Fallback) Sample(ctx context.Context) (Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.onSecondary && f.Clock.Now().Before(f.retryAt) {
+		return f.Secondary.Sample(ctx)
+	}
+	snap, err := f.Primary.Sample(ctx)
+	if err == nil {
+		if f.onSecondary {
+			f.onSecondary = false
+			f.switched(f.Primary.Name(), nil)
+		}
+		return snap, nil
+	}
+	if ctx.Err() != nil {
+		// Cancelled, not a sampler failure.
+		return snap, err
+	}
+	f.retryAt = f.Clock.Now().Add(f.RetryAfter)
+	if !f.onSecondary {
+		f.onSecondary = true
+		f.switched(f.Secondary.Name(), err)
+	}
+	return f.Secondary.Sample(ctx)
+}
+
+func (f *Fallback) switched(active string, err error) {
+	if f.OnSwitch != nil {
+		f.OnSwitch(active, err)
+	}
+}
+
+func (f *Fallback) Close() error {
+	err := f.Primary.Close()
+	if err2 := f.Secondary.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package sampling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gpu-reclaimer-agent/internal/clock"
+)
+
+// scriptedSampler fails while err is set and counts its calls.
+type scriptedSampler struct {
+	name   string
+	err    error
+	calls  int
+	closed int
+}
+
+func (s *scriptedSampler) Name() string { return s.name }
+
+func (s *scriptedSampler) Sample(ctx context.Context) (Snapshot, error) {
+	s.calls++
+	if s.err != nil {
+		return Snapshot{}, s.err
+	}
+	return Snapshot{GPUs: []GPUSnapshot{{UUID: s.name}}}, nil
+}
+
+func (s *scriptedSampler) Close() error {
+	s.closed++
+	return nil
+}
+
+// switchRecord is one OnSwitch call; err is "" when returning to the primary.
+type switchRecord struct {
+	active string
+	err    string
+}
+
+func newTestFallback() (*Fallback, *scriptedSampler, *scriptedSampler, *clock.Fake, *[]switchRecord) {
+	primary := &scriptedSampler{name: "nvml"}
+	secondary := &scriptedSampler{name: "nvidia-smi"}
+	clk := clock.NewFake(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	var switches []switchRecord
+	f := NewFallback(primary, secondary)
+	f.Clock = clk
+	f.OnSwitch = func(active string, err error) {
+		rec := switchRecord{active: active}
+		if err != nil {
+			rec.err = err.Error()
+		}
+		switches = append(switches, rec)
+	}
+	return f, primary, secondary, clk, &switches
+}
+
+// sampledFrom returns which sampler produced a snapshot.
+func sampledFrom(t *testing.T, f *Fallback) string {
+	t.Helper()
+	snap, err := f.Sample(context.Background())
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	return snap.GPUs[0].UUID
+}
+
+func TestFallbackSwitchesOnPrimaryFailure(t *testing.T) {
+	f, primary, _, _, switches := newTestFallback()
+
+	if got := sampledFrom(t, f); got != "nvml" {
+		t.Fatalf("healthy primary: sampled from %s", got)
+	}
+	if len(*switches) != 0 {
+		t.Fatalf("switched with a healthy primary: %v", *switches)
+	}
+
+	primary.err = errors.New("nvml: driver/library version mismatch")
+	if got := sampledFrom(t, f); got != "nvidia-smi" {
+		t.Fatalf("failed primary: sampled from %s", got)
+	}
+	if want := fmt.Sprint([]switchRecord{{"nvidia-smi", primary.err.Error()}}); fmt.Sprint(*switches) != want {
+		t.Errorf("switches = %v, want %v", *switches, want)
+	}
+
+	// Staying on the secondary is not another switch.
+	sampledFrom(t, f)
+	if len(*switches) != 1 {
+		t.Errorf("switches = %v, want one", *switches)
+	}
+}
+
+func TestFallbackRetriesPrimaryAfterRetryAfter(t *testing.T) {
+	f, primary, _, clk, switches := newTestFallback()
+	f.RetryAfter = 5 * time.Minute
+	primary.err = errors.New("nvml: not initialized")
+	sampledFrom(t, f)
+
+	// Within RetryAfter the primary is left alone.
+	clk.Advance(4 * time.Minute)
+	sampledFrom(t, f)
+	if primary.calls != 1 {
+		t.Fatalf("primary sampled %d times before RetryAfter, want 1", primary.calls)
+	}
+
+	// A retry that fails again stays on the secondary and re-arms the timer.
+	clk.Advance(time.Minute)
+	if got := sampledFrom(t, f); got != "nvidia-smi" || primary.calls != 2 {
+		t.Fatalf("retry: sampled from %s, primary calls %d", got, primary.calls)
+	}
+	clk.Advance(4 * time.Minute)
+	sampledFrom(t, f)
+	if primary.calls != 2 {
+		t.Fatalf("primary retried %d times, want the timer re-armed by the failed retry", primary.calls)
+	}
+
+	primary.err = nil
+	clk.Advance(time.Minute)
+	if got := sampledFrom(t, f); got != "nvml" {
+		t.Fatalf("recovered primary: sampled from %s", got)
+	}
+	want := fmt.Sprint([]switchRecord{{"nvidia-smi", "nvml: not initialized"}, {"nvml", ""}})
+	if fmt.Sprint(*switches) != want {
+		t.Errorf("switches = %v, want %v", *switches, want)
+	}
+}
+
+func TestFallbackCancelledContextIsNotAFailure(t *testing.T) {
+	f, primary, secondary, _, switches := newTestFallback()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary.err = ctx.Err()
+
+	if _, err := f.Sample(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sample = %v, want context.Canceled", err)
+	}
+	if secondary.calls != 0 || len(*switches) != 0 {
+		t.Errorf("fell back on cancellation: secondary calls %d, switches %v", secondary.calls, *switches)
+	}
+
+	// The next sample with a live context still uses the primary.
+	primary.err = nil
+	if got := sampledFrom(t, f); got != "nvml" {
+		t.Errorf("after cancellation: sampled from %s", got)
+	}
+}
+
+func TestFallbackCloseClosesBoth(t *testing.T) {
+	f, primary, secondary, _, _ := newTestFallback()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if primary.closed != 1 || secondary.closed != 1 {
+		t.Errorf("closed primary %d, secondary %d times; want 1 each", primary.closed, secondary.closed)
+	}
+}
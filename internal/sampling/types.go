@@ -1,20 +1,131 @@
 package sampling
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// JSON tags define the recording format (see package recording); renaming a
+// field breaks replay of existing recordings.
+
+// Samplers report values they could not read (nvidia-smi "[N/A]", NVML
+// errors, missing exporter fields) through the *Unknown flags rather than
+// as 0, which would read as "idle". The flags are negative so recordings
+// made before they existed still decode as known.
+
 type GPUProcess struct {
-	PID       int
-	UsedBytes uint64
+	PID              int    `json:"pid"`
+	UsedBytes        uint64 `json:"usedBytes"`
+	UsedBytesUnknown bool   `json:"usedBytesUnknown,omitempty"`
+
+	// Per-process SM and memory utilization in percent over the last
+	// sample period; valid when HasUtil is set.
+	HasUtil bool   `json:"hasUtil,omitempty"`
+	SMUtil  uint32 `json:"smUtil,omitempty"`
+	MemUtil uint32 `json:"memUtil,omitempty"`
+	EncUtil uint32 `json:"encUtil,omitempty"`
+	DecUtil uint32 `json:"decUtil,omitempty"`
+
+	// Lifetime statistics from driver accounting (nvidia-smi sampler with
+	// accounting mode on); informational, never an idle signal.
+	HasAccounting bool          `json:"hasAccounting,omitempty"`
+	AvgSMUtil     uint32        `json:"avgSMUtil,omitempty"`
+	AvgMemUtil    uint32        `json:"avgMemUtil,omitempty"`
+	MaxUsedBytes  uint64        `json:"maxUsedBytes,omitempty"`
+	RunTime       time.Duration `json:"runTime,omitempty"`
 }
 
+// MIGDevice identifies a MIG slice of a GPU.
+type MIGDevice struct {
+	ParentUUID    string `json:"parentUUID,omitempty"`
+	GPUInstanceID int    `json:"gpuInstanceID"`
+	// ComputeInstanceID is -1 when the source only reports per GPU
+	// instance (DCGM).
+	ComputeInstanceID int `json:"computeInstanceID"`
+}
+
+// GPUSnapshot describes a whole GPU or, when MIG is set, one MIG device; for
+// a MIG device Index is the parent GPU's index and UUID the MIG UUID, or
+// empty when the sampler cannot tell it (nvidia-smi, DCGM). It is never the
+// parent's UUID, which is in MIG.ParentUUID: the device plugin allocates
+// MIG devices by their own UUID.
 type GPUSnapshot struct {
-	Index         int
-	UUID          string
-	UtilGPU       uint32
-	UtilMem       uint32
-	MemUsedBytes  uint64
-	MemTotalBytes uint64
-	ComputeProcs  []GPUProcess
+	Index         int          `json:"index"`
+	UUID          string       `json:"uuid"`
+	MIG           *MIGDevice   `json:"mig,omitempty"`
+	UtilGPU       uint32       `json:"utilGPU"`
+	UtilMem       uint32       `json:"utilMem"`
+	MemUsedBytes  uint64       `json:"memUsedBytes"`
+	MemTotalBytes uint64       `json:"memTotalBytes"`
+	ComputeProcs  []GPUProcess `json:"computeProcs,omitempty"`
+
+	UtilGPUUnknown  bool `json:"utilGPUUnknown,omitempty"`
+	UtilMemUnknown  bool `json:"utilMemUnknown,omitempty"`
+	MemUsedUnknown  bool `json:"memUsedUnknown,omitempty"`
+	MemTotalUnknown bool `json:"memTotalUnknown,omitempty"`
+
+	// Profiling activity in percent of time (DCGM sampler only); valid
+	// when HasActivity is set.
+	HasActivity     bool    `json:"hasActivity,omitempty"`
+	SMActivePct     float64 `json:"smActivePct,omitempty"`
+	TensorActivePct float64 `json:"tensorActivePct,omitempty"`
+
+	// Error is set when the device could not be read (e.g. fell off the
+	// bus); its values and process list are then unknown, not empty.
+	Error string `json:"error,omitempty"`
+}
+
+// UnhealthyGPU is the entry for a device that could not be read.
+func UnhealthyGPU(index int, uuid, err string) GPUSnapshot {
+	return GPUSnapshot{
+		Index: index, UUID: uuid, Error: err,
+		UtilGPUUnknown: true, UtilMemUnknown: true, MemUsedUnknown: true, MemTotalUnknown: true,
+	}
+}
+
+// Healthy reports whether the device was read successfully.
+func (g GPUSnapshot) Healthy() bool { return g.Error == "" }
+
+// ID names the device uniquely within a snapshot: the GPU index, or
+// "<index>/gi<GI>[/ci<CI>]" for a MIG device.
+func (g GPUSnapshot) ID() string {
+	if g.MIG == nil {
+		return strconv.Itoa(g.Index)
+	}
+	if g.MIG.ComputeInstanceID < 0 {
+		return fmt.Sprintf("%d/gi%d", g.Index, g.MIG.GPUInstanceID)
+	}
+	return fmt.Sprintf("%d/gi%d/ci%d", g.Index, g.MIG.GPUInstanceID, g.MIG.ComputeInstanceID)
+}
+
+// BusyPct is the idle signal for the GPU: SM activity when the sampler
+// provides it, otherwise utilization.gpu, which counts any kernel running
+// during the sample period as fully busy. ok is false when neither is known.
+func (g GPUSnapshot) BusyPct() (pct float64, ok bool) {
+	if !g.Healthy() {
+		return 0, false
+	}
+	if g.HasActivity {
+		return g.SMActivePct, true
+	}
+	if g.UtilGPUUnknown {
+		return 0, false
+	}
+	return float64(g.UtilGPU), true
 }
 
 type Snapshot struct {
-	GPUs []GPUSnapshot
+	GPUs []GPUSnapshot `json:"gpus"`
+}
+
+// Unhealthy returns the devices that could not be read.
+func (s Snapshot) Unhealthy() []GPUSnapshot {
+	var out []GPUSnapshot
+	for _, g := range s.GPUs {
+		if !g.Healthy() {
+			out = append(out, g)
+		}
+	}
+	return out
 }
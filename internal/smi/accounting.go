@@ -0,0 +1,70 @@
+package smi
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// accountedApp is one running process from --query-accounted-apps. The
+// figures are averages over the process lifetime, so they describe a job
+// but are not an idle signal for the last sample period.
+type accountedApp struct {
+	GPUUUID      string
+	PID          int
+	AvgSMUtil    uint32
+	AvgMemUtil   uint32
+	MaxUsedBytes uint64
+	RunTime      time.Duration
+}
+
+// queryAccountedApps needs accounting mode (nvidia-smi -am 1); without it
+// the query fails or returns nothing, which callers ignore.
+func (s *Sampler) queryAccountedApps(ctx context.Context) ([]accountedApp, error) {
+	qctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := s.run(qctx,
+		"--query-accounted-apps=gpu_uuid,pid,gpu_utilization,mem_utilization,max_memory_usage,time,is_running",
+		"--format=csv,noheader,nounits",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseAccountedApps(out)
+}
+
+// parseAccountedApps keeps only running processes: the accounting buffer
+// also holds exited ones whose PIDs may since have been reused.
+func parseAccountedApps(out []byte) ([]accountedApp, error) {
+	lines, err := readCSVLines(out)
+	if err != nil {
+		return nil, err
+	}
+	var apps []accountedApp
+	for _, cols := range lines {
+		if len(cols) < 7 || cols[6] != "1" {
+			continue
+		}
+		pid, err := strconv.Atoi(cols[1])
+		if err != nil {
+			continue
+		}
+		app := accountedApp{GPUUUID: cols[0], PID: pid}
+		// Informational only, so unknown values are simply left at 0.
+		if v, ok := parseUint(cols[2]); ok {
+			app.AvgSMUtil = uint32(v)
+		}
+		if v, ok := parseUint(cols[3]); ok {
+			app.AvgMemUtil = uint32(v)
+		}
+		if v, ok := parseUint(cols[4]); ok {
+			app.MaxUsedBytes = v * 1024 * 1024
+		}
+		if v, ok := parseUint(cols[5]); ok {
+			app.RunTime = time.Duration(v) * time.Millisecond
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
@@ -0,0 +1,119 @@
+package smi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+// MIGPlacement is the MIG instance a process runs on.
+type MIGPlacement struct {
+	GPU               int
+	GPUInstanceID     int
+	ComputeInstanceID int
+}
+
+// MIGPlacements maps PIDs on MIG devices to their GPU/compute instance.
+// --query-compute-apps has no instance columns, so this parses the process
+// table of the plain `nvidia-smi` output:
+//
+//	|  GPU   GI   CI        PID   Type   Process name            GPU Memory |
+//	|    0    1    0      12345      C   python                     1234MiB |
+//
+// Processes on non-MIG GPUs show "N/A" instance IDs and are left out.
+func (s *Sampler) MIGPlacements(ctx context.Context) (map[int]MIGPlacement, error) {
+	qctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := s.run(qctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseMIGPlacements(out), nil
+}
+
+func parseMIGPlacements(out []byte) map[int]MIGPlacement {
+	res := map[int]MIGPlacement{}
+	inProcs := false
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.Contains(line, "Processes:") {
+			inProcs = true
+			continue
+		}
+		if !inProcs || !strings.HasPrefix(line, "|") {
+			continue
+		}
+		fields := strings.Fields(strings.Trim(line, "|"))
+		if len(fields) < 4 {
+			continue
+		}
+		gpu, err1 := strconv.Atoi(fields[0])
+		gi, err2 := strconv.Atoi(fields[1])
+		ci, err3 := strconv.Atoi(fields[2])
+		pid, err4 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			// Header, separator, "N/A" instances or "No running processes".
+			continue
+		}
+		res[pid] = MIGPlacement{GPU: gpu, GPUInstanceID: gi, ComputeInstanceID: ci}
+	}
+	return res
+}
+
+// migSuspected reports whether a GPU with processes has no utilization,
+// which is how nvidia-smi presents a MIG-enabled GPU.
+func migSuspected(gpus []sampling.GPUSnapshot) bool {
+	for _, g := range gpus {
+		if g.UtilGPUUnknown && len(g.ComputeProcs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMIG moves processes of MIG-enabled GPUs onto one entry per MIG
+// device. nvidia-smi reports no utilization or memory for MIG devices, so
+// those entries are unknown and never judged idle, but processes are
+// attributed to the right slice. pmon has no per-process utilization on MIG
+// either and prints "-", read as 0, so moved processes drop it: otherwise
+// the slice would be judged on that 0. The MIG UUID is not in the process
+// table, so UUID stays empty.
+func splitMIG(gpus []sampling.GPUSnapshot, place map[int]MIGPlacement) []sampling.GPUSnapshot {
+	out := make([]sampling.GPUSnapshot, 0, len(gpus))
+	var migs []sampling.GPUSnapshot
+	migIdx := map[MIGPlacement]int{}
+	for _, g := range gpus {
+		keep := g.ComputeProcs[:0:0]
+		for _, p := range g.ComputeProcs {
+			pl, ok := place[p.PID]
+			if !ok || pl.GPU != g.Index {
+				keep = append(keep, p)
+				continue
+			}
+			i, ok := migIdx[pl]
+			if !ok {
+				migs = append(migs, sampling.GPUSnapshot{
+					Index: g.Index,
+					MIG:   &sampling.MIGDevice{ParentUUID: g.UUID, GPUInstanceID: pl.GPUInstanceID, ComputeInstanceID: pl.ComputeInstanceID},
+
+					UtilGPUUnknown: true, UtilMemUnknown: true, MemUsedUnknown: true, MemTotalUnknown: true,
+				})
+				i = len(migs) - 1
+				migIdx[pl] = i
+			}
+			p.HasUtil = false
+			p.SMUtil, p.MemUtil, p.EncUtil, p.DecUtil = 0, 0, 0, 0
+			migs[i].ComputeProcs = append(migs[i].ComputeProcs, p)
+		}
+		g.ComputeProcs = keep
+		out = append(out, g)
+	}
+	return append(out, migs...)
+}
@@ -0,0 +1,84 @@
+package smi
+
+import (
+	"reflect"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/idle"
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+func TestParseMIGPlacements(t *testing.T) {
+	got := parseMIGPlacements(readTestdata(t, "nvidia-smi-mig.txt"))
+	want := map[int]MIGPlacement{
+		31001: {GPU: 0, GPUInstanceID: 1, ComputeInstanceID: 0},
+		31002: {GPU: 0, GPUInstanceID: 2, ComputeInstanceID: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMIGPlacements = %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitMIG(t *testing.T) {
+	parent := "GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10"
+	gpus := []sampling.GPUSnapshot{
+		// pmon prints "-" for processes on MIG devices, read as 0.
+		{Index: 0, UUID: parent, UtilGPUUnknown: true, ComputeProcs: []sampling.GPUProcess{
+			{PID: 31001, HasUtil: true}, {PID: 31002, HasUtil: true}, {PID: 31009, HasUtil: true},
+		}},
+		{Index: 1, UUID: "GPU-8f2a1d44-0b3c-4e5d-a6f7-1b2c3d4e5f60", ComputeProcs: []sampling.GPUProcess{{PID: 31003, HasUtil: true}}},
+	}
+	got := splitMIG(gpus, parseMIGPlacements(readTestdata(t, "nvidia-smi-mig.txt")))
+
+	var ids []string
+	for _, g := range got {
+		ids = append(ids, g.ID())
+		if g.MIG == nil {
+			continue
+		}
+		// Never the parent's UUID: the device plugin allocates MIG UUIDs.
+		if g.UUID != "" || g.MIG.ParentUUID != parent {
+			t.Errorf("%s: UUID %q parent %q", g.ID(), g.UUID, g.MIG.ParentUUID)
+		}
+		if _, ok := g.BusyPct(); ok {
+			t.Errorf("%s: MIG slice has a known load", g.ID())
+		}
+		// What the agent judges the slice on: its processes' load, falling
+		// back to the card's. Neither is known, so even a 100% threshold
+		// must not find the slice idle.
+		u := sliceUsage(g)
+		if pct, ok := u.BusyPct(); ok {
+			t.Errorf("%s: agent sees a known load of %v%%", g.ID(), pct)
+		}
+		if idle.IsIdle([]idle.GPUUsage{u}, 100) {
+			t.Errorf("%s: MIG slice judged idle", g.ID())
+		}
+	}
+	// Processes left on a whole GPU keep their pmon reading.
+	if p := got[1].ComputeProcs[0]; !p.HasUtil {
+		t.Errorf("GPU 1 process lost its pmon reading: %+v", p)
+	}
+	if want := []string{"0", "1", "0/gi1/ci0", "0/gi2/ci0"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("devices = %v, want %v", ids, want)
+	}
+	// The unplaced process stays on the whole-GPU entry.
+	if len(got[0].ComputeProcs) != 1 || got[0].ComputeProcs[0].PID != 31009 {
+		t.Errorf("GPU 0 keeps %+v", got[0].ComputeProcs)
+	}
+}
+
+// sliceUsage builds the usage the agent derives from a device entry: the
+// summed per-process SM utilization when every process has one, else the
+// card's.
+func sliceUsage(g sampling.GPUSnapshot) idle.GPUUsage {
+	busy, ok := g.BusyPct()
+	u := idle.GPUUsage{GPU: g.Index, Device: g.ID(), CardBusyPct: busy, CardKnown: ok, ProcKnown: true}
+	for _, p := range g.ComputeProcs {
+		if !p.HasUtil {
+			u.ProcKnown = false
+			continue
+		}
+		u.ProcBusyPct += float64(p.SMUtil)
+	}
+	return u
+}
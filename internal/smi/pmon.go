@@ -0,0 +1,116 @@
+package smi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pmonRow is one process line of `nvidia-smi pmon -c 1`. Utilization
+// columns are nil when the column is absent from this driver's output.
+type pmonRow struct {
+	GPU     int
+	PID     int
+	Type    string
+	SM      *uint32
+	Mem     *uint32
+	Enc     *uint32
+	Dec     *uint32
+	Command string
+}
+
+type pmonKey struct {
+	gpu, pid int
+}
+
+func (s *Sampler) queryPmon(ctx context.Context) (map[pmonKey]pmonRow, error) {
+	// pmon samples for about a second before printing.
+	qctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := s.run(qctx, "pmon", "-c", "1", "-s", "u")
+	if err != nil {
+		return nil, err
+	}
+	return parsePmon(out)
+}
+
+// parsePmon parses pmon output. Columns are located by the header line
+// ("# gpu pid type sm mem enc dec [jpg ofa] command"), since drivers add
+// columns over time. A "-" in a utilization column means the process
+// submitted no work during the sample and reads as 0%; rows without a PID
+// (GPUs with no processes) are skipped.
+func parsePmon(out []byte) (map[pmonKey]pmonRow, error) {
+	cols := map[string]int{}
+	rows := map[pmonKey]pmonRow{}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(strings.TrimPrefix(line, "#"))
+			// The first header names the columns; the second holds units.
+			if len(cols) == 0 && len(fields) > 0 && fields[0] == "gpu" {
+				for i, f := range fields {
+					cols[strings.ToLower(f)] = i
+				}
+			}
+			continue
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("pmon: data before header: %q", line)
+		}
+		fields := strings.Fields(line)
+		gi, ok1 := cols["gpu"]
+		pi, ok2 := cols["pid"]
+		if !ok1 || !ok2 || len(fields) <= gi || len(fields) <= pi {
+			return nil, fmt.Errorf("pmon: short line %q", line)
+		}
+		if fields[pi] == "-" {
+			continue
+		}
+		gpu, err := strconv.Atoi(fields[gi])
+		if err != nil {
+			return nil, fmt.Errorf("pmon: bad gpu in %q", line)
+		}
+		pid, err := strconv.Atoi(fields[pi])
+		if err != nil {
+			return nil, fmt.Errorf("pmon: bad pid in %q", line)
+		}
+		row := pmonRow{GPU: gpu, PID: pid}
+		if i, ok := cols["type"]; ok && i < len(fields) {
+			row.Type = fields[i]
+		}
+		row.SM = pmonPct(fields, cols, "sm")
+		row.Mem = pmonPct(fields, cols, "mem")
+		row.Enc = pmonPct(fields, cols, "enc")
+		row.Dec = pmonPct(fields, cols, "dec")
+		if i, ok := cols["command"]; ok && i < len(fields) {
+			row.Command = strings.Join(fields[i:], " ")
+		}
+		rows[pmonKey{gpu, pid}] = row
+	}
+	return rows, sc.Err()
+}
+
+func pmonPct(fields []string, cols map[string]int, name string) *uint32 {
+	i, ok := cols[name]
+	if !ok || i >= len(fields) {
+		return nil
+	}
+	var v uint32
+	if fields[i] != "-" {
+		n, err := strconv.ParseUint(fields[i], 10, 32)
+		if err != nil {
+			return nil
+		}
+		v = uint32(n)
+	}
+	return &v
+}
@@ -0,0 +1,100 @@
+package smi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func pct(v uint32) *uint32 { return &v }
+
+func TestParsePmon(t *testing.T) {
+	cases := []struct {
+		file string
+		want map[pmonKey]pmonRow
+	}{
+		{
+			// R470: sm mem enc dec only.
+			file: "pmon-470.txt",
+			want: map[pmonKey]pmonRow{
+				{0, 12345}: {GPU: 0, PID: 12345, Type: "C", SM: pct(45), Mem: pct(20), Enc: pct(0), Dec: pct(0), Command: "python"},
+				{0, 12399}: {GPU: 0, PID: 12399, Type: "G", SM: pct(0), Mem: pct(0), Enc: pct(0), Dec: pct(0), Command: "Xorg"},
+			},
+		},
+		{
+			// R535: jpg and ofa columns before command.
+			file: "pmon-535.txt",
+			want: map[pmonKey]pmonRow{
+				{0, 23456}: {GPU: 0, PID: 23456, Type: "C", SM: pct(87), Mem: pct(41), Enc: pct(0), Dec: pct(0), Command: "python3"},
+				{0, 23457}: {GPU: 0, PID: 23457, Type: "C", SM: pct(0), Mem: pct(0), Enc: pct(0), Dec: pct(0), Command: "python3"},
+				{1, 23500}: {GPU: 1, PID: 23500, Type: "C", SM: pct(3), Mem: pct(1), Enc: pct(12), Dec: pct(9), Command: "ffmpeg"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			got, err := parsePmon(readTestdata(t, c.file))
+			if err != nil {
+				t.Fatalf("parsePmon: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("parsePmon =\n%+v\nwant\n%+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParsePmonMissingColumns(t *testing.T) {
+	out := []byte("# gpu pid type mem command\n# Idx # C/G % name\n 0 7 C 5 python\n")
+	got, err := parsePmon(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := got[pmonKey{0, 7}]
+	if row.SM != nil || row.Mem == nil || *row.Mem != 5 {
+		t.Errorf("row = %+v, want SM absent and Mem 5", row)
+	}
+}
+
+func TestParsePmonErrors(t *testing.T) {
+	for name, out := range map[string]string{
+		"no header": " 0 7 C 5 5 - - python\n",
+		"bad pid":   "# gpu pid type sm command\n 0 x C 5 python\n",
+	} {
+		if _, err := parsePmon([]byte(out)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseAccountedApps(t *testing.T) {
+	got, err := parseAccountedApps(readTestdata(t, "accounted-apps-535.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []accountedApp{
+		{
+			GPUUUID: "GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10", PID: 23456,
+			AvgSMUtil: 63, AvgMemUtil: 20, MaxUsedBytes: 15360 << 20, RunTime: time.Hour,
+		},
+		// Exited processes (is_running 0) are dropped; placeholders read as 0.
+		{
+			GPUUUID: "GPU-8f2a1d44-0b3c-4e5d-a6f7-1b2c3d4e5f60", PID: 23457,
+			RunTime: 12 * time.Second,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAccountedApps =\n%+v\nwant\n%+v", got, want)
+	}
+}
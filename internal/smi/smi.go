@@ -1,11 +1,12 @@
 package smi
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -16,13 +17,19 @@ import (
 
 type Sampler struct {
 	BinaryPath string
+
+	// ProcessUtil adds per-process utilization from `pmon -c 1`.
+	ProcessUtil bool
+	// Accounting adds lifetime statistics from --query-accounted-apps when
+	// accounting mode is enabled on the GPUs.
+	Accounting bool
 }
 
 func New(binaryPath string) *Sampler {
 	if strings.TrimSpace(binaryPath) == "" {
 		binaryPath = "nvidia-smi"
 	}
-	return &Sampler{BinaryPath: binaryPath}
+	return &Sampler{BinaryPath: binaryPath, ProcessUtil: true, Accounting: true}
 }
 
 func (s *Sampler) Name() string { return "nvidia-smi" }
@@ -58,16 +65,91 @@ func (s *Sampler) Sample(ctx context.Context) (sampling.Snapshot, error) {
 		if gpu == nil {
 			continue
 		}
-		gpu.ComputeProcs = append(gpu.ComputeProcs, sampling.GPUProcess{PID: p.PID, UsedBytes: p.UsedBytes})
+		gpu.ComputeProcs = append(gpu.ComputeProcs, sampling.GPUProcess{PID: p.PID, UsedBytes: p.UsedBytes, UsedBytesUnknown: p.UsedUnknown})
+	}
+	if len(procs) > 0 {
+		s.addProcessDetail(ctx, gpus, byUUID)
+	}
+	if migSuspected(gpus) {
+		// Best-effort: without placements processes stay on the parent GPU,
+		// whose utilization is [N/A] under MIG and thus never idle.
+		if place, err := s.MIGPlacements(ctx); err == nil && len(place) > 0 {
+			gpus = splitMIG(gpus, place)
+		}
 	}
 
 	return sampling.Snapshot{GPUs: gpus}, nil
 }
 
+// addProcessDetail enriches compute processes with pmon utilization and
+// accounting statistics. Both are best-effort: when unavailable the
+// processes keep HasUtil unset and the card-level signal is used.
+func (s *Sampler) addProcessDetail(ctx context.Context, gpus []sampling.GPUSnapshot, byUUID map[string]*sampling.GPUSnapshot) {
+	if s.ProcessUtil {
+		if rows, err := s.queryPmon(ctx); err == nil {
+			for gi := range gpus {
+				g := &gpus[gi]
+				for pi := range g.ComputeProcs {
+					p := &g.ComputeProcs[pi]
+					row, ok := rows[pmonKey{g.Index, p.PID}]
+					if !ok || row.SM == nil {
+						// Not seen by pmon: unknown, not idle.
+						continue
+					}
+					p.HasUtil, p.SMUtil = true, *row.SM
+					if row.Mem != nil {
+						p.MemUtil = *row.Mem
+					}
+					if row.Enc != nil {
+						p.EncUtil = *row.Enc
+					}
+					if row.Dec != nil {
+						p.DecUtil = *row.Dec
+					}
+				}
+			}
+		}
+	}
+	if s.Accounting {
+		if apps, err := s.queryAccountedApps(ctx); err == nil {
+			for _, app := range apps {
+				g := byUUID[app.GPUUUID]
+				if g == nil {
+					continue
+				}
+				for pi := range g.ComputeProcs {
+					p := &g.ComputeProcs[pi]
+					if p.PID != app.PID {
+						continue
+					}
+					p.HasAccounting = true
+					p.AvgSMUtil, p.AvgMemUtil = app.AvgSMUtil, app.AvgMemUtil
+					p.MaxUsedBytes, p.RunTime = app.MaxUsedBytes, app.RunTime
+				}
+			}
+		}
+	}
+}
+
+// ComputeProcs lists compute processes per GPU UUID via
+// --query-compute-apps; no running processes is an empty map.
+func (s *Sampler) ComputeProcs(ctx context.Context) (map[string][]sampling.GPUProcess, error) {
+	rows, err := s.queryComputeProcs(ctx)
+	if err != nil && !errors.Is(err, errNoResults) {
+		return nil, err
+	}
+	out := map[string][]sampling.GPUProcess{}
+	for _, p := range rows {
+		out[p.GPUUUID] = append(out[p.GPUUUID], sampling.GPUProcess{PID: p.PID, UsedBytes: p.UsedBytes, UsedBytesUnknown: p.UsedUnknown})
+	}
+	return out, nil
+}
+
 type procRow struct {
-	GPUUUID   string
-	PID       int
-	UsedBytes uint64
+	GPUUUID     string
+	PID         int
+	UsedBytes   uint64
+	UsedUnknown bool
 }
 
 var errNoResults = errors.New("nvidia-smi no results")
@@ -100,28 +182,39 @@ func (s *Sampler) queryGPUs(ctx context.Context) ([]sampling.GPUSnapshot, error)
 		return nil, err
 	}
 
-	lines := readCSVLines(out)
+	return parseGPUs(out)
+}
+
+// parseGPUs parses --query-gpu output. Placeholders such as "[N/A]" mark the
+// value unknown instead of reading as 0; a row whose index or UUID cannot be
+// read fails the whole sample, since judging a pod on only some of its GPUs
+// could make it look idle.
+func parseGPUs(out []byte) ([]sampling.GPUSnapshot, error) {
+	lines, err := readCSVLines(out)
+	if err != nil {
+		return nil, err
+	}
 	gpus := make([]sampling.GPUSnapshot, 0, len(lines))
 	for _, cols := range lines {
 		if len(cols) < 6 {
-			continue
+			return nil, fmt.Errorf("nvidia-smi --query-gpu: expected 6 columns, got %d: %q", len(cols), strings.Join(cols, ", "))
+		}
+		idx, err := strconv.Atoi(cols[0])
+		if err != nil || !known(cols[1]) {
+			return nil, fmt.Errorf("nvidia-smi --query-gpu: unreadable index/uuid: %q", strings.Join(cols, ", "))
 		}
-		idx, _ := strconv.Atoi(cols[0])
-		uuid := cols[1]
-		utilGPU, _ := strconv.Atoi(cols[2])
-		utilMem, _ := strconv.Atoi(cols[3])
-		memUsedMiB, _ := strconv.ParseUint(cols[4], 10, 64)
-		memTotalMiB, _ := strconv.ParseUint(cols[5], 10, 64)
-
-		gpus = append(gpus, sampling.GPUSnapshot{
-			Index:         idx,
-			UUID:          uuid,
-			UtilGPU:       uint32(utilGPU),
-			UtilMem:       uint32(utilMem),
-			MemUsedBytes:  memUsedMiB * 1024 * 1024,
-			MemTotalBytes: memTotalMiB * 1024 * 1024,
-			ComputeProcs:  nil,
-		})
+		g := sampling.GPUSnapshot{Index: idx, UUID: cols[1]}
+		var ok bool
+		var v uint64
+		v, ok = parseUint(cols[2])
+		g.UtilGPU, g.UtilGPUUnknown = uint32(v), !ok
+		v, ok = parseUint(cols[3])
+		g.UtilMem, g.UtilMemUnknown = uint32(v), !ok
+		v, ok = parseUint(cols[4])
+		g.MemUsedBytes, g.MemUsedUnknown = v*1024*1024, !ok
+		v, ok = parseUint(cols[5])
+		g.MemTotalBytes, g.MemTotalUnknown = v*1024*1024, !ok
+		gpus = append(gpus, g)
 	}
 	return gpus, nil
 }
@@ -138,32 +231,82 @@ func (s *Sampler) queryComputeProcs(ctx context.Context) ([]procRow, error) {
 		return nil, err
 	}
 
-	lines := readCSVLines(out)
+	return parseComputeProcs(out)
+}
+
+// parseComputeProcs parses --query-compute-apps output. used_gpu_memory is
+// "[N/A]" in some containers and on MIG; the PID and GPU must be readable.
+func parseComputeProcs(out []byte) ([]procRow, error) {
+	lines, err := readCSVLines(out)
+	if err != nil {
+		return nil, err
+	}
 	rows := make([]procRow, 0, len(lines))
 	for _, cols := range lines {
 		if len(cols) < 3 {
-			continue
+			return nil, fmt.Errorf("nvidia-smi --query-compute-apps: expected 3 columns, got %d: %q", len(cols), strings.Join(cols, ", "))
 		}
-		pid, _ := strconv.Atoi(cols[1])
-		memMiB, _ := strconv.ParseUint(cols[2], 10, 64)
-		rows = append(rows, procRow{GPUUUID: cols[0], PID: pid, UsedBytes: memMiB * 1024 * 1024})
+		pid, err := strconv.Atoi(cols[1])
+		if err != nil || pid <= 0 || !known(cols[0]) {
+			return nil, fmt.Errorf("nvidia-smi --query-compute-apps: unreadable gpu/pid: %q", strings.Join(cols, ", "))
+		}
+		memMiB, ok := parseUint(cols[2])
+		rows = append(rows, procRow{GPUUUID: cols[0], PID: pid, UsedBytes: memMiB * 1024 * 1024, UsedUnknown: !ok})
 	}
 	return rows, nil
 }
 
-func readCSVLines(b []byte) [][]string {
-	scanner := bufio.NewScanner(bytes.NewReader(b))
-	out := [][]string{}
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+// readCSVLines reads nvidia-smi's "csv,noheader,nounits" output. Fields are
+// quoted when they contain commas (e.g. GPU names), so a plain split is not
+// enough.
+func readCSVLines(b []byte) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.LazyQuotes = true
+	var out [][]string
+	for {
+		cols, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("nvidia-smi csv: %w", err)
+		}
+		if len(cols) == 1 && strings.TrimSpace(cols[0]) == "" {
 			continue
 		}
-		cols := strings.Split(line, ",")
 		for i := range cols {
 			cols[i] = strings.TrimSpace(cols[i])
 		}
 		out = append(out, cols)
 	}
-	return out
+}
+
+// known reports whether an nvidia-smi value is real data rather than a
+// placeholder like "[N/A]", "[Not Supported]", "[Insufficient Permissions]",
+// "[Unknown Error]" or "[GPU requires reset]".
+func known(v string) bool {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.HasPrefix(v, "[") {
+		return false
+	}
+	switch strings.ToLower(v) {
+	case "n/a", "not supported", "unknown":
+		return false
+	}
+	return true
+}
+
+// parseUint parses a known numeric value; ok is false for placeholders and
+// garbage alike.
+func parseUint(v string) (uint64, bool) {
+	if !known(v) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
 }
@@ -0,0 +1,87 @@
+package smi
+
+import (
+	"reflect"
+	"testing"
+
+	"gpu-reclaimer-agent/internal/sampling"
+)
+
+const mib = 1 << 20
+
+func TestParseGPUs(t *testing.T) {
+	got, err := parseGPUs(readTestdata(t, "query-gpu.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sampling.GPUSnapshot{
+		{Index: 0, UUID: "GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10", MemUsedBytes: 15361 * mib, MemTotalBytes: 81559 * mib},
+		{
+			Index: 1, UUID: "GPU-8f2a1d44-0b3c-4e5d-a6f7-1b2c3d4e5f60", MemUsedBytes: 4 * mib, MemTotalBytes: 81559 * mib,
+			UtilGPUUnknown: true, UtilMemUnknown: true,
+		},
+		{
+			Index: 2, UUID: "GPU-0a1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9", UtilMem: 12,
+			UtilGPUUnknown: true, MemUsedUnknown: true, MemTotalUnknown: true,
+		},
+		{Index: 3, UUID: "GPU-1f2e3d4c-5b6a-7980-a1b2-c3d4e5f6a7b8", UtilGPU: 37, UtilMem: 5, MemUsedBytes: 1024 * mib, MemTotalBytes: 40960 * mib},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGPUs =\n%+v\nwant\n%+v", got, want)
+	}
+	// An unknown utilization must never read as idle.
+	if _, ok := got[1].BusyPct(); ok {
+		t.Error("BusyPct known for a [N/A] utilization")
+	}
+}
+
+func TestParseGPUsRejectsUnreadableIdentity(t *testing.T) {
+	for name, out := range map[string]string{
+		"index":   "[N/A], GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10, 0, 0, 1, 2\n",
+		"uuid":    "0, [Unknown Error], 0, 0, 1, 2\n",
+		"columns": "0, GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10, 0\n",
+	} {
+		if _, err := parseGPUs([]byte(out)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseComputeProcs(t *testing.T) {
+	got, err := parseComputeProcs(readTestdata(t, "query-compute-apps.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []procRow{
+		{GPUUUID: "GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10", PID: 23456, UsedBytes: 15360 * mib},
+		{GPUUUID: "GPU-8f2a1d44-0b3c-4e5d-a6f7-1b2c3d4e5f60", PID: 23457, UsedUnknown: true},
+		{GPUUUID: "GPU-1f2e3d4c-5b6a-7980-a1b2-c3d4e5f6a7b8", PID: 31000, UsedUnknown: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseComputeProcs =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseComputeProcsRejectsUnreadablePID(t *testing.T) {
+	out := []byte("GPU-5b1e6c33-6f6d-2c8e-7b1a-9d0c4f3e2a10, [N/A], 100\n")
+	if _, err := parseComputeProcs(out); err == nil {
+		t.Error("expected an error for an unreadable pid")
+	}
+}
+
+func TestKnown(t *testing.T) {
+	for v, want := range map[string]bool{
+		"12":                         true,
+		"GPU-abc":                    true,
+		"":                           false,
+		"[N/A]":                      false,
+		"N/A":                        false,
+		"[Not Supported]":            false,
+		"[Insufficient Permissions]": false,
+		"[GPU requires reset]":       false,
+	} {
+		if got := known(v); got != want {
+			t.Errorf("known(%q) = %v, want %v", v, got, want)
+		}
+	}
+}